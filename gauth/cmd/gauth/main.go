@@ -7,19 +7,38 @@
 //	gauth fetch <scope> Fetch a service token (photos, youtube, gmail, drive, or custom scope)
 //	gauth checkin      Force device check-in (get new GSF ID)
 //	gauth serve [port] Start HTTP token server (default: 8080)
+//	gauth accounts list|use <email>|remove <email>  Manage multiple signed-in accounts
+//	gauth config rekey Re-encrypt stored credentials with the current passphrase
+//	gauth proto decode --schema foo.proto --message pkg.Msg  Decode a captured
+//	                   protobuf payload using a real schema instead of guessing
+//
+// Setting GAUTH_PASSPHRASE (or storing a passphrase in the OS keyring under
+// service "gauth") makes Save/SaveAccount write master_token/security_token/
+// email encrypted under a secrets_enc YAML key instead of in cleartext —
+// see internal/config/secrets.go.
+//
+// Every subcommand above (except accounts/trust/cache) accepts a global
+// --account <email> flag (or GAUTH_ACCOUNT env var) to operate as a
+// non-default account — see internal/config/accounts.go.
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/nicksrandall/gauth/internal/auth"
 	"github.com/nicksrandall/gauth/internal/checkin"
 	"github.com/nicksrandall/gauth/internal/config"
 	"github.com/nicksrandall/gauth/internal/login"
+	"github.com/nicksrandall/gauth/internal/proto"
 	"github.com/nicksrandall/gauth/internal/server"
+	"github.com/nicksrandall/gauth/internal/store"
 )
 
 func main() {
@@ -30,35 +49,148 @@ func main() {
 		os.Exit(1)
 	}
 
+	args, account := extractAccountFlag(os.Args[1:])
+	if account == "" {
+		account = os.Getenv("GAUTH_ACCOUNT")
+	}
+	if len(args) == 0 {
+		printUsage()
+		os.Exit(1)
+	}
+
 	cfg := config.Load()
-	cmd := os.Args[1]
+	cmd := args[0]
+	rest := args[1:]
 
 	switch cmd {
 	case "login":
-		cmdLogin(cfg)
+		useDevice := false
+		provider := ""
+		useVault := false
+		for i := 0; i < len(rest); i++ {
+			switch {
+			case rest[i] == "--device":
+				useDevice = true
+			case rest[i] == "--provider" && i+1 < len(rest):
+				provider = rest[i+1]
+				i++
+			case strings.HasPrefix(rest[i], "--provider="):
+				provider = strings.TrimPrefix(rest[i], "--provider=")
+			case rest[i] == "--vault":
+				useVault = true
+			}
+		}
+		cfg.ApplyAccount(account)
+		cmdLogin(cfg, useDevice, provider, useVault)
 	case "token":
+		cfg.ApplyAccount(account)
 		cmdToken(cfg)
 	case "fetch":
-		if len(os.Args) < 3 {
-			fmt.Println("Usage: gauth fetch <scope>")
+		if len(rest) < 1 {
+			fmt.Println("Usage: gauth fetch <scope> [--force]")
 			fmt.Println("Examples: gauth fetch photos")
 			fmt.Println("          gauth fetch \"oauth2:https://www.googleapis.com/auth/youtube\"")
 			os.Exit(1)
 		}
-		cmdFetch(cfg, os.Args[2])
+		scope := ""
+		force := false
+		for _, a := range rest {
+			if a == "--force" {
+				force = true
+				continue
+			}
+			if scope == "" {
+				scope = a
+			}
+		}
+		cfg.ApplyAccount(account)
+		cmdFetch(cfg, scope, force)
+	case "cache":
+		if len(rest) < 1 || rest[0] != "clear" {
+			fmt.Println("Usage: gauth cache clear [scope]")
+			os.Exit(1)
+		}
+		scope := ""
+		if len(rest) >= 2 {
+			scope = rest[1]
+		}
+		cmdCacheClear(scope)
 	case "checkin":
+		cfg.ApplyAccount(account)
 		cmdCheckin(cfg)
 	case "serve":
 		port := 8080
-		if len(os.Args) >= 3 {
-			p, err := strconv.Atoi(os.Args[2])
+		if len(rest) >= 1 {
+			p, err := strconv.Atoi(rest[0])
 			if err != nil {
-				fmt.Printf("Invalid port: %s\n", os.Args[2])
+				fmt.Printf("Invalid port: %s\n", rest[0])
 				os.Exit(1)
 			}
 			port = p
 		}
+		cfg.ApplyAccount(account)
 		cmdServe(cfg, port)
+	case "trust":
+		if len(rest) < 1 || rest[0] != "install" {
+			fmt.Println("Usage: gauth trust install")
+			os.Exit(1)
+		}
+		cmdTrustInstall()
+	case "config":
+		if len(rest) < 1 || rest[0] != "rekey" {
+			fmt.Println("Usage: gauth config rekey")
+			os.Exit(1)
+		}
+		cfg.ApplyAccount(account)
+		cmdConfigRekey(cfg)
+	case "proto":
+		if len(rest) < 1 || rest[0] != "decode" {
+			fmt.Println("Usage: gauth proto decode --schema foo.proto --message pkg.Msg [--in file] (reads raw bytes from stdin if --in is omitted)")
+			os.Exit(1)
+		}
+		schema, message, in := "", "", ""
+		for i := 1; i < len(rest); i++ {
+			switch {
+			case rest[i] == "--schema" && i+1 < len(rest):
+				schema = rest[i+1]
+				i++
+			case rest[i] == "--message" && i+1 < len(rest):
+				message = rest[i+1]
+				i++
+			case rest[i] == "--in" && i+1 < len(rest):
+				in = rest[i+1]
+				i++
+			}
+		}
+		if schema == "" || message == "" {
+			fmt.Println("Usage: gauth proto decode --schema foo.proto --message pkg.Msg [--in file]")
+			os.Exit(1)
+		}
+		cmdProtoDecode(schema, message, in)
+	case "accounts":
+		if len(rest) < 1 {
+			fmt.Println("Usage: gauth accounts list|use <email>|remove <email>")
+			os.Exit(1)
+		}
+		switch rest[0] {
+		case "list":
+			cmdAccountsList()
+		case "use":
+			if len(rest) < 2 {
+				fmt.Println("Usage: gauth accounts use <email>")
+				os.Exit(1)
+			}
+			cmdAccountsUse(rest[1])
+		case "remove":
+			if len(rest) < 2 {
+				fmt.Println("Usage: gauth accounts remove <email>")
+				os.Exit(1)
+			}
+			cmdAccountsRemove(rest[1])
+		default:
+			fmt.Println("Usage: gauth accounts list|use <email>|remove <email>")
+			os.Exit(1)
+		}
 	default:
 		fmt.Printf("Unknown command: %s\n\n", cmd)
 		printUsage()
@@ -66,7 +198,32 @@ func main() {
 	}
 }
 
-func cmdLogin(cfg *config.Config) {
+// extractAccountFlag pulls "--account <email>" (or "--account=<email>") out
+// of args, returning the remaining args and the selected email (empty if
+// not given). This runs before subcommand dispatch so every subcommand gets
+// account selection for free instead of parsing it individually.
+func extractAccountFlag(args []string) (rest []string, account string) {
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--account" && i+1 < len(args):
+			account = args[i+1]
+			i++
+		case strings.HasPrefix(a, "--account="):
+			account = strings.TrimPrefix(a, "--account=")
+		default:
+			rest = append(rest, a)
+		}
+	}
+	return rest, account
+}
+
+func cmdLogin(cfg *config.Config, useDevice bool, providerName string, useVault bool) {
+	if useVault {
+		cmdLoginVault(cfg)
+		return
+	}
+
 	// Step 1: Check-in (if not done)
 	if !cfg.HasRegistration() {
 		fmt.Println("📡 Step 1/3: Device check-in...")
@@ -78,9 +235,18 @@ func cmdLogin(cfg *config.Config) {
 		fmt.Printf("📡 Device already registered (GSF ID: %s)\n", cfg.AndroidID)
 	}
 
-	// Step 2: WebView login
-	fmt.Println("🌐 Step 2/3: Opening Google sign-in...")
-	result, err := login.RunWebViewLogin(cfg)
+	// Step 2: sign in — the device-code flow, or a login.Provider
+	// (WebView2 on Windows, a loopback browser redirect everywhere else).
+	var result *login.LoginResult
+	var err error
+	if useDevice {
+		fmt.Println("🔑 Step 2/3: Device code sign-in...")
+		result, err = login.RunDeviceFlowLogin(cfg)
+	} else {
+		provider, name := resolveLoginProvider(providerName)
+		fmt.Printf("🌐 Step 2/3: Opening Google sign-in (%s)...\n", name)
+		result, err = provider.Start(cfg)
+	}
 	if err != nil {
 		log.Fatalf("Login failed: %v", err)
 	}
@@ -108,6 +274,10 @@ func cmdLogin(cfg *config.Config) {
 	if err := cfg.Save(); err != nil {
 		log.Fatalf("Failed to save config: %v", err)
 	}
+	if err := cfg.SaveAccount(); err != nil {
+		log.Fatalf("Failed to save account: %v", err)
+	}
+	saveToVault(cfg, result.OAuthToken)
 
 	fmt.Println()
 	fmt.Println("✅ Login successful!")
@@ -119,6 +289,56 @@ func cmdLogin(cfg *config.Config) {
 	fmt.Println("  gauth serve           — Start token server")
 }
 
+// saveToVault persists the just-completed login to the OS-keychain-backed
+// store.Vault so a later `gauth login --vault` can skip sign-in entirely.
+// A missing or unavailable vault (e.g. no secret-tool on this Linux box) is
+// logged and otherwise ignored — cfg.Save/SaveAccount above already
+// persisted the login, so the vault is strictly a nice-to-have.
+func saveToVault(cfg *config.Config, oauthToken string) {
+	vault, err := store.NewVault()
+	if err != nil {
+		log.Printf("note: not saving to OS credential vault: %v", err)
+		return
+	}
+	creds := store.Credentials{
+		Email:         cfg.Email,
+		OAuthToken:    oauthToken,
+		MasterToken:   cfg.MasterToken,
+		AndroidID:     cfg.AndroidID,
+		SecurityToken: cfg.SecurityToken,
+	}
+	if err := vault.Save(cfg.Email, creds); err != nil {
+		log.Printf("note: failed to save credentials to OS credential vault: %v", err)
+	}
+}
+
+// cmdLoginVault implements `gauth login --vault`: try the OS-keychain
+// vault first (store.LoadOrLogin reuses a cached master token if it still
+// validates against Google, and falls back to a full check-in + sign-in +
+// exchange otherwise), then persist the result the same way a normal login
+// does so every other command sees it.
+func cmdLoginVault(cfg *config.Config) {
+	if cfg.Email == "" {
+		log.Fatalf("gauth login --vault requires a known account; pass --account <email>")
+	}
+
+	fmt.Printf("🔐 Checking OS credential vault for %s...\n", cfg.Email)
+	creds, err := store.LoadOrLogin(cfg, cfg.Email)
+	if err != nil {
+		log.Fatalf("Vault login failed: %v", err)
+	}
+
+	if err := cfg.Save(); err != nil {
+		log.Fatalf("Failed to save config: %v", err)
+	}
+	if err := cfg.SaveAccount(); err != nil {
+		log.Fatalf("Failed to save account: %v", err)
+	}
+
+	fmt.Println("✅ Login successful (vault)!")
+	fmt.Printf("   Email: %s\n", creds.Email)
+}
+
 func cmdToken(cfg *config.Config) {
 	if !cfg.HasMasterToken() {
 		fmt.Println("❌ Not logged in. Run: gauth login")
@@ -130,7 +350,7 @@ func cmdToken(cfg *config.Config) {
 	fmt.Printf("Device:       %s (%s)\n", cfg.Device.Model, cfg.Device.Fingerprint)
 }
 
-func cmdFetch(cfg *config.Config, scope string) {
+func cmdFetch(cfg *config.Config, scope string, force bool) {
 	if !cfg.HasMasterToken() {
 		fmt.Println("❌ Not logged in. Run: gauth login")
 		os.Exit(1)
@@ -147,7 +367,13 @@ func cmdFetch(cfg *config.Config, scope string) {
 		fmt.Printf("📱 Fetching token for scope: %s...\n", scope)
 	}
 
-	resp, err := auth.FetchServiceToken(cfg, scope, appPkg, appSig)
+	var resp *auth.Response
+	var err error
+	if force {
+		resp, err = auth.GetServiceTokenForce(cfg, scope, appPkg, appSig)
+	} else {
+		resp, err = auth.GetServiceToken(cfg, scope, appPkg, appSig)
+	}
 	if err != nil {
 		log.Fatalf("Token fetch failed: %v", err)
 	}
@@ -166,6 +392,17 @@ func cmdFetch(cfg *config.Config, scope string) {
 	fmt.Printf("✅ Token (%s):\n%s\n", tokenType, resp.Auth)
 }
 
+func cmdCacheClear(scope string) {
+	if err := auth.ClearTokenCache(scope); err != nil {
+		log.Fatalf("Cache clear failed: %v", err)
+	}
+	if scope == "" {
+		fmt.Println("✅ Cleared all cached service tokens.")
+	} else {
+		fmt.Printf("✅ Cleared cached tokens for scope: %s\n", scope)
+	}
+}
+
 func cmdCheckin(cfg *config.Config) {
 	fmt.Println("📡 Performing device check-in...")
 	if err := doCheckin(cfg); err != nil {
@@ -175,6 +412,38 @@ func cmdCheckin(cfg *config.Config) {
 	fmt.Printf("   Security Token: %s\n", cfg.SecurityToken)
 }
 
+// cmdProtoDecode decodes a captured protobuf payload (check-in or MCS
+// traffic, typically) against a user-supplied .proto file or
+// FileDescriptorSet, printing the result as indented JSON keyed by real
+// field names instead of the heuristic decoder's field-number keys.
+func cmdProtoDecode(schema, message, inPath string) {
+	if err := os.Setenv("GAUTH_PROTO_DESCRIPTORS", schema); err != nil {
+		log.Fatalf("set GAUTH_PROTO_DESCRIPTORS: %v", err)
+	}
+
+	var data []byte
+	var err error
+	if inPath == "" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(inPath)
+	}
+	if err != nil {
+		log.Fatalf("read input: %v", err)
+	}
+
+	decoded, err := proto.DecodeMessageAs(data, message)
+	if err != nil {
+		log.Fatalf("decode: %v", err)
+	}
+
+	out, err := json.MarshalIndent(decoded, "", "  ")
+	if err != nil {
+		log.Fatalf("marshal result: %v", err)
+	}
+	fmt.Println(string(out))
+}
+
 func cmdServe(cfg *config.Config, port int) {
 	if !cfg.HasMasterToken() {
 		fmt.Println("⚠️  Warning: Not logged in. Token endpoints will fail until you run: gauth login")
@@ -184,6 +453,18 @@ func cmdServe(cfg *config.Config, port int) {
 	}
 }
 
+func cmdTrustInstall() {
+	if err := server.EnsureServerCert(); err != nil {
+		log.Fatalf("Failed to generate server cert: %v", err)
+	}
+	certPath := server.ServerCertPath()
+	fmt.Printf("🔐 Installing %s into the local trust store...\n", certPath)
+	if err := server.InstallTrust(certPath); err != nil {
+		log.Fatalf("Trust install failed: %v", err)
+	}
+	fmt.Println("✅ Trusted. `gauth serve` with tls = \"auto\" will no longer show a browser warning.")
+}
+
 func doCheckin(cfg *config.Config) error {
 	result, err := checkin.Checkin(cfg)
 	if err != nil {
@@ -191,19 +472,120 @@ func doCheckin(cfg *config.Config) error {
 	}
 	cfg.AndroidID = fmt.Sprintf("%x", result.AndroidID)
 	cfg.SecurityToken = fmt.Sprintf("%d", result.SecurityToken)
-	return cfg.Save()
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+	return cfg.SaveAccount()
+}
+
+// cmdConfigRekey re-saves cfg and the account store, re-encrypting every
+// account's secrets_enc blob with whatever passphrase is currently
+// configured (GAUTH_PASSPHRASE or the OS keyring) — or writing plaintext
+// if none is. Since cfg was already decrypted with that same passphrase by
+// config.Load/ApplyAccount above, this both rotates the salt/nonce for an
+// unchanged passphrase and migrates a still-plaintext account the first
+// time a passphrase is configured. Changing to a genuinely new passphrase
+// takes two runs: one with the old passphrase set to decrypt, then one
+// with the new passphrase set to re-encrypt — if decryption didn't
+// succeed, HasMasterToken catches it before anything gets overwritten.
+func cmdConfigRekey(cfg *config.Config) {
+	if !cfg.HasMasterToken() {
+		log.Fatalf("No credentials loaded; if secrets_enc is already set, GAUTH_PASSPHRASE/keyring must match the passphrase it was encrypted with before rekey can re-encrypt it")
+	}
+	if err := cfg.Save(); err != nil {
+		log.Fatalf("Rekey failed: %v", err)
+	}
+	if err := cfg.SaveAccount(); err != nil {
+		log.Fatalf("Rekey failed: %v", err)
+	}
+	if config.PassphraseConfigured() {
+		fmt.Println("✅ Config and accounts re-encrypted with the current passphrase.")
+	} else {
+		fmt.Println("✅ Config and accounts saved in plaintext (no passphrase configured).")
+	}
+}
+
+func cmdAccountsList() {
+	store := config.LoadAccountStore()
+	if len(store.Accounts) == 0 {
+		fmt.Println("No accounts yet. Run: gauth login")
+		return
+	}
+	emails := make([]string, 0, len(store.Accounts))
+	for email := range store.Accounts {
+		emails = append(emails, email)
+	}
+	sort.Strings(emails)
+	for _, email := range emails {
+		marker := "  "
+		if email == store.Default {
+			marker = "* "
+		}
+		fmt.Printf("%s%s\n", marker, email)
+	}
+}
+
+func cmdAccountsUse(email string) {
+	store := config.LoadAccountStore()
+	if err := store.SetDefault(email); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := store.Save(); err != nil {
+		log.Fatalf("Failed to save account store: %v", err)
+	}
+	fmt.Printf("✅ Default account is now %s\n", email)
+}
+
+func cmdAccountsRemove(email string) {
+	store := config.LoadAccountStore()
+	store.Remove(email)
+	if err := store.Save(); err != nil {
+		log.Fatalf("Failed to save account store: %v", err)
+	}
+	fmt.Printf("✅ Removed %s\n", email)
 }
 
 func printUsage() {
 	fmt.Println("gauth — Google Auth Tool")
 	fmt.Println()
 	fmt.Println("Usage:")
-	fmt.Println("  gauth login          Sign in with Google (opens WebView)")
+	fmt.Println("  gauth login          Sign in with Google (WebView2 on Windows, browser loopback elsewhere)")
+	fmt.Println("  gauth login --provider webview2|loopback  Force a specific sign-in provider")
+	fmt.Println("  gauth login --device Sign in via device code (no browser UI)")
+	fmt.Println("  gauth login --vault --account <email>  Reuse a cached OS-keychain login if")
+	fmt.Println("                       still valid, signing in fresh otherwise (internal/store)")
 	fmt.Println("  gauth token          Show stored account info and master token")
-	fmt.Println("  gauth fetch <scope>  Fetch a service token")
+	fmt.Println("  gauth fetch <scope>  Fetch a service token (cached until it's about to expire)")
+	fmt.Println("  gauth fetch <scope> --force  Bypass the cache and re-exchange")
+	fmt.Println("  gauth cache clear [scope]    Drop cached service tokens")
 	fmt.Println("  gauth checkin        Force device check-in")
 	fmt.Println("  gauth serve [port]   Start HTTP token server (default: 8080)")
+	fmt.Println("                       Binds 127.0.0.1 unless [auth] in gauth.toml configures")
+	fmt.Println("                       api_keys, client_ca, or hashcash_enabled (see below)")
+	fmt.Println("  gauth trust install  Trust the auto-generated HTTPS cert (tls = \"auto\")")
+	fmt.Println("  gauth accounts list          List signed-in accounts (* = default)")
+	fmt.Println("  gauth accounts use <email>   Make <email> the default account")
+	fmt.Println("  gauth accounts remove <email> Forget a signed-in account")
+	fmt.Println("  gauth config rekey   Re-encrypt (or encrypt for the first time) stored")
+	fmt.Println("                       credentials with the current GAUTH_PASSPHRASE/keyring passphrase")
+	fmt.Println("  gauth proto decode --schema foo.proto --message pkg.Msg [--in file]")
+	fmt.Println("                       Decode a raw protobuf payload (stdin, or --in) using real")
+	fmt.Println("                       field names from foo.proto (or a FileDescriptorSet)")
 	fmt.Println()
 	fmt.Println("Scope shortcuts: photos, youtube, gmail, drive, calendar")
 	fmt.Println("Custom scope:    gauth fetch \"oauth2:https://...\"")
+	fmt.Println()
+	fmt.Println("Global flag: --account <email>  (or GAUTH_ACCOUNT env var)")
+	fmt.Println("  Selects which signed-in account to use instead of the default.")
+	fmt.Println()
+	fmt.Println("gauth serve access control — [auth] table in ~/.config/gauth/gauth.toml:")
+	fmt.Println("  api_keys = [\"...\"]        Require Authorization: Bearer <key> on every request")
+	fmt.Println("  client_ca = \"/path/ca.pem\" Require a client cert signed by this CA (needs tls on)")
+	fmt.Println("  hashcash_enabled = true    Gate /api/token behind a proof-of-work challenge")
+	fmt.Println("  hashcash_difficulty = 20   Required leading zero bits (default 20)")
+	fmt.Println("  rate_limit_per_second = 5  Per-key/IP request rate limit (0 = disabled)")
+	fmt.Println("  rate_limit_burst = 10")
+	fmt.Println("  Env overrides: GAUTH_AUTH_API_KEYS (comma-separated), GAUTH_AUTH_CLIENT_CA,")
+	fmt.Println("  GAUTH_AUTH_HASHCASH, GAUTH_AUTH_HASHCASH_DIFFICULTY,")
+	fmt.Println("  GAUTH_AUTH_RATE_LIMIT_PER_SECOND, GAUTH_AUTH_RATE_LIMIT_BURST")
 }
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package main
+
+import "github.com/nicksrandall/gauth/internal/login"
+
+// resolveLoginProvider picks the login.Provider cmdLogin uses. WebView2
+// isn't available outside Windows, so LoopbackProvider is the only option
+// here regardless of what --provider asks for.
+func resolveLoginProvider(name string) (login.Provider, string) {
+	return login.LoopbackProvider{}, "loopback"
+}
@@ -0,0 +1,15 @@
+//go:build windows
+
+package main
+
+import "github.com/nicksrandall/gauth/internal/login"
+
+// resolveLoginProvider picks the login.Provider cmdLogin uses: the explicit
+// --provider override if given, else WebView2Provider, since this is the
+// Windows build where it's available.
+func resolveLoginProvider(name string) (login.Provider, string) {
+	if name == "loopback" {
+		return login.LoopbackProvider{}, "loopback"
+	}
+	return login.WebView2Provider{}, "webview2"
+}
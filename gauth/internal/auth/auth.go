@@ -8,6 +8,7 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -91,6 +92,36 @@ func FetchServiceToken(cfg *config.Config, scope, appPackage, appSig string) (*R
 	return doAuthRequest(cfg, form)
 }
 
+// RefreshMaster re-validates a previously-issued master token by exchanging
+// it for a fresh ac2dm service token. A successful response confirms the
+// master token is still accepted by Google (and carries a new Expiry); a
+// 401/403 means it's been revoked. Used by RefreshManager to periodically
+// check on stored sessions without requiring the caller's own master token
+// to be the one active in cfg.
+func RefreshMaster(cfg *config.Config, masterToken string) (*Response, error) {
+	withToken := *cfg
+	withToken.MasterToken = masterToken
+	return FetchServiceToken(&withToken, "ac2dm", "com.google.android.gms", GoogleSig)
+}
+
+// FetchOAuth2ForScope exchanges cfg's master token for an OAuth2 access
+// token scoped to scope (a bare scope URL; the "oauth2:" prefix the /auth
+// endpoint expects is added automatically), acting as saEmail rather than
+// cfg.Email when saEmail is non-empty — for scopes like
+// https://www.googleapis.com/auth/androidpublisher that are granted to a
+// service account identity, not whichever Google account is signed into
+// gauth.
+func FetchOAuth2ForScope(cfg *config.Config, scope, saEmail string) (*Response, error) {
+	withEmail := *cfg
+	if saEmail != "" {
+		withEmail.Email = saEmail
+	}
+	if !strings.HasPrefix(scope, "oauth2:") {
+		scope = "oauth2:" + scope
+	}
+	return FetchServiceToken(&withEmail, scope, "com.google.android.gms", GoogleSig)
+}
+
 // Common Google app signature.
 const GoogleSig = "24bb24c05e47e0aefa68a58a766179d9b613a600"
 
@@ -200,6 +231,10 @@ func parseAuthResponse(body string) *Response {
 			r.AccountID = value
 		case "issueAdvice":
 			r.IssueAdvice = value
+		case "Expiry":
+			if exp, err := strconv.ParseInt(value, 10, 64); err == nil {
+				r.Expiry = exp
+			}
 		case "grantedScopes":
 			r.GrantedScopes = value
 		case "Error":
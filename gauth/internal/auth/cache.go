@@ -0,0 +1,420 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nicksrandall/gauth/internal/config"
+	"github.com/nicksrandall/gauth/internal/tokencache"
+)
+
+const tokenCacheFileName = "gauth_token_cache.json"
+
+// memCache is an in-memory LRU sitting in front of defaultTokenCache,
+// serving the hot /api/token path without the disk read and cross-process
+// file lock defaultTokenCache.Get/Set take on every call. defaultTokenCache
+// is still the source of truth across process restarts and between a CLI
+// invocation and a running `gauth serve`; memCache just avoids paying for
+// it on every request.
+var memCache = tokencache.NewCache(2048)
+
+// cacheEntry is one cached service token, keyed by (email, appPackage, scope).
+type cacheEntry struct {
+	Auth          string `json:"auth"`
+	Email         string `json:"email"`
+	FirstName     string `json:"first_name,omitempty"`
+	LastName      string `json:"last_name,omitempty"`
+	AccountID     string `json:"account_id,omitempty"`
+	Expiry        int64  `json:"expiry"`
+	GrantedScopes string `json:"granted_scopes"`
+	IssueAdvice   string `json:"issue_advice"`
+	FetchedAt     int64  `json:"fetched_at"`
+}
+
+type cacheFile struct {
+	Entries map[string]cacheEntry `json:"entries"`
+}
+
+// TokenCache persists service tokens in a JSON file next to the config, so
+// repeated fetches for the same (email, appPackage, scope) don't re-exchange
+// the master token every time. Reads and writes are serialized with a
+// sibling lock file so concurrent `gauth fetch` CLI invocations and a
+// running `gauth serve` don't corrupt the cache.
+type TokenCache struct {
+	path string
+}
+
+// NewTokenCache returns a TokenCache backed by the file at path.
+func NewTokenCache(path string) *TokenCache {
+	return &TokenCache{path: path}
+}
+
+func defaultTokenCachePath() string {
+	return filepath.Join(filepath.Dir(config.ConfigPath()), tokenCacheFileName)
+}
+
+var defaultTokenCache = NewTokenCache(defaultTokenCachePath())
+
+func cacheKey(email, appPackage, scope string) string {
+	return email + "|" + appPackage + "|" + scope
+}
+
+func (c *TokenCache) load() (cacheFile, error) {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cacheFile{Entries: map[string]cacheEntry{}}, nil
+		}
+		return cacheFile{}, err
+	}
+	var cf cacheFile
+	if err := json.Unmarshal(data, &cf); err != nil {
+		// Corrupt cache file — treat it as empty rather than failing every fetch.
+		return cacheFile{Entries: map[string]cacheEntry{}}, nil
+	}
+	if cf.Entries == nil {
+		cf.Entries = map[string]cacheEntry{}
+	}
+	return cf, nil
+}
+
+func (c *TokenCache) save(cf cacheFile) error {
+	data, err := json.MarshalIndent(cf, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal token cache: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return fmt.Errorf("create cache dir: %w", err)
+	}
+	return os.WriteFile(c.path, data, 0600)
+}
+
+// Get returns the cached entry for (email, appPackage, scope), if any.
+func (c *TokenCache) Get(email, appPackage, scope string) (cacheEntry, bool) {
+	unlock, err := acquireFileLock(c.path)
+	if err != nil {
+		return cacheEntry{}, false
+	}
+	defer unlock()
+
+	cf, err := c.load()
+	if err != nil {
+		return cacheEntry{}, false
+	}
+	entry, ok := cf.Entries[cacheKey(email, appPackage, scope)]
+	return entry, ok
+}
+
+// Set writes through a fresh entry for (email, appPackage, scope).
+func (c *TokenCache) Set(email, appPackage, scope string, entry cacheEntry) error {
+	unlock, err := acquireFileLock(c.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cf, err := c.load()
+	if err != nil {
+		return err
+	}
+	cf.Entries[cacheKey(email, appPackage, scope)] = entry
+	return c.save(cf)
+}
+
+// Clear removes cached entries whose scope matches, or every entry if scope
+// is empty.
+func (c *TokenCache) Clear(scope string) error {
+	unlock, err := acquireFileLock(c.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cf, err := c.load()
+	if err != nil {
+		return err
+	}
+	if scope == "" {
+		cf.Entries = map[string]cacheEntry{}
+	} else {
+		for key := range cf.Entries {
+			if strings.HasSuffix(key, "|"+scope) {
+				delete(cf.Entries, key)
+			}
+		}
+	}
+	return c.save(cf)
+}
+
+// findByToken scans every entry for one whose Auth equals token, returning
+// the scope it was issued for alongside the entry. Used by introspection,
+// which identifies a token by its value rather than by (email, appPackage,
+// scope).
+func (c *TokenCache) findByToken(token string) (scope string, entry cacheEntry, ok bool) {
+	unlock, err := acquireFileLock(c.path)
+	if err != nil {
+		return "", cacheEntry{}, false
+	}
+	defer unlock()
+
+	cf, err := c.load()
+	if err != nil {
+		return "", cacheEntry{}, false
+	}
+	for key, e := range cf.Entries {
+		if e.Auth != token {
+			continue
+		}
+		if parts := strings.SplitN(key, "|", 3); len(parts) == 3 {
+			scope = parts[2]
+		}
+		return scope, e, true
+	}
+	return "", cacheEntry{}, false
+}
+
+// latestEntry returns the most recently fetched cache entry for email,
+// across every appPackage/scope it's been used with. Identity fields
+// (FirstName/LastName/AccountID) don't vary by scope, so any entry will do.
+func (c *TokenCache) latestEntry(email string) (entry cacheEntry, ok bool) {
+	unlock, err := acquireFileLock(c.path)
+	if err != nil {
+		return cacheEntry{}, false
+	}
+	defer unlock()
+
+	cf, err := c.load()
+	if err != nil {
+		return cacheEntry{}, false
+	}
+	prefix := email + "|"
+	for key, e := range cf.Entries {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if !ok || e.FetchedAt > entry.FetchedAt {
+			entry, ok = e, true
+		}
+	}
+	return entry, ok
+}
+
+// --- advisory file lock ---
+
+const (
+	lockFileSuffix  = ".lock"
+	lockAcquireWait = 5 * time.Second
+	lockPollDelay   = 50 * time.Millisecond
+)
+
+// acquireFileLock takes an exclusive, cross-process advisory lock on
+// path+".lock" using O_EXCL create, retrying until lockAcquireWait elapses —
+// at which point it assumes the lock is stale (owner crashed) and steals it
+// rather than blocking forever. The returned func releases the lock.
+func acquireFileLock(path string) (func(), error) {
+	lockPath := path + lockFileSuffix
+	deadline := time.Now().Add(lockAcquireWait)
+
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("acquire cache lock: %w", err)
+		}
+		if time.Now().After(deadline) {
+			os.Remove(lockPath)
+			continue
+		}
+		time.Sleep(lockPollDelay)
+	}
+}
+
+// GetServiceToken returns a cached service token for (cfg.Email, appPackage,
+// scope) if it's still valid for at least another minute, otherwise fetches
+// a fresh one via FetchServiceToken and writes it through to the cache. Use
+// this instead of FetchServiceToken directly anywhere a token might be
+// requested repeatedly — cmdFetch and the HTTP token server both do.
+func GetServiceToken(cfg *config.Config, scope, appPackage, appSig string) (*Response, error) {
+	return getServiceToken(cfg, scope, appPackage, appSig, false)
+}
+
+// GetServiceTokenForce always re-exchanges, bypassing any cached entry, and
+// writes the fresh result back through. Backs `gauth fetch --force`.
+func GetServiceTokenForce(cfg *config.Config, scope, appPackage, appSig string) (*Response, error) {
+	return getServiceToken(cfg, scope, appPackage, appSig, true)
+}
+
+func getServiceToken(cfg *config.Config, scope, appPackage, appSig string, force bool) (*Response, error) {
+	key := tokencache.Key(cfg.Email, appPackage, appSig, scope)
+
+	if !force {
+		if entry, ok := memCache.Get(key); ok {
+			switch entry.Status(time.Now()) {
+			case tokencache.Fresh:
+				return serviceResponseFromEntry(cfg.Email, entry), nil
+			case tokencache.NearExpiry:
+				memCache.RecordAsyncRefresh()
+				go func() {
+					if _, err := refreshServiceToken(cfg, scope, appPackage, appSig, key); err != nil {
+						log.Printf("[auth] background refresh failed: %v", err)
+					}
+				}()
+				return serviceResponseFromEntry(cfg.Email, entry), nil
+			}
+			// tokencache.Expired falls through to the blocking refresh below.
+		} else if entry, ok := defaultTokenCache.Get(cfg.Email, appPackage, scope); ok && entry.Expiry > 0 && time.Now().Unix() < entry.Expiry-60 {
+			memEntry := tokencache.Entry{Token: entry.Auth, GrantedScopes: entry.GrantedScopes, ExpiresAt: time.Unix(entry.Expiry, 0)}
+			memCache.Set(key, memEntry)
+			return serviceResponseFromEntry(cfg.Email, memEntry), nil
+		}
+	}
+
+	memCache.RecordBlockingRefresh()
+	return refreshServiceToken(cfg, scope, appPackage, appSig, key)
+}
+
+func serviceResponseFromEntry(email string, e tokencache.Entry) *Response {
+	return &Response{Auth: e.Token, Email: email, Expiry: e.ExpiresAt.Unix(), GrantedScopes: e.GrantedScopes}
+}
+
+// refreshServiceToken does the actual round trip to Google and, on
+// success, writes the result through to both memCache (the hot path) and
+// defaultTokenCache (the durable, cross-process one), timing the fetch for
+// the gauth_tokencache_fetch_duration_seconds metric.
+func refreshServiceToken(cfg *config.Config, scope, appPackage, appSig, key string) (*Response, error) {
+	start := time.Now()
+	resp, err := FetchServiceToken(cfg, scope, appPackage, appSig)
+	memCache.RecordFetch(time.Since(start))
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.Auth != "" && resp.Expiry > 0 {
+		memCache.Set(key, tokencache.Entry{Token: resp.Auth, GrantedScopes: resp.GrantedScopes, ExpiresAt: time.Unix(resp.Expiry, 0)})
+
+		entry := cacheEntry{
+			Auth:          resp.Auth,
+			Email:         cfg.Email,
+			FirstName:     resp.FirstName,
+			LastName:      resp.LastName,
+			AccountID:     resp.AccountID,
+			Expiry:        resp.Expiry,
+			GrantedScopes: resp.GrantedScopes,
+			IssueAdvice:   resp.IssueAdvice,
+			FetchedAt:     time.Now().Unix(),
+		}
+		if err := defaultTokenCache.Set(cfg.Email, appPackage, scope, entry); err != nil {
+			log.Printf("[auth] cache write failed: %v", err)
+		}
+	}
+
+	return resp, nil
+}
+
+// GetOAuth2ForScope returns a cached OAuth2 token for (saEmail or
+// cfg.Email, "oauth2", scope) if it's still valid for at least another
+// minute, otherwise fetches a fresh one via FetchOAuth2ForScope and writes
+// it through to the cache — the same caching GetServiceToken does for
+// master-token exchanges, reused here so /api/verify-purchase doesn't
+// re-exchange on every call.
+func GetOAuth2ForScope(cfg *config.Config, scope, saEmail string) (*Response, error) {
+	cacheEmail := cfg.Email
+	if saEmail != "" {
+		cacheEmail = saEmail
+	}
+
+	if entry, ok := defaultTokenCache.Get(cacheEmail, "oauth2", scope); ok {
+		if entry.Expiry > 0 && time.Now().Unix() < entry.Expiry-60 {
+			return &Response{Auth: entry.Auth, Email: cacheEmail, Expiry: entry.Expiry}, nil
+		}
+	}
+
+	resp, err := FetchOAuth2ForScope(cfg, scope, saEmail)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.Auth != "" && resp.Expiry > 0 {
+		entry := cacheEntry{Auth: resp.Auth, Email: cacheEmail, Expiry: resp.Expiry, FetchedAt: time.Now().Unix()}
+		if err := defaultTokenCache.Set(cacheEmail, "oauth2", scope, entry); err != nil {
+			log.Printf("[auth] cache write failed: %v", err)
+		}
+	}
+	return resp, nil
+}
+
+// ClearTokenCache removes cached entries for scope, or every entry if scope
+// is empty, from both memCache and defaultTokenCache. Backs `gauth cache
+// clear [scope]` and DELETE /api/cache.
+func ClearTokenCache(scope string) error {
+	memCache.DeleteScope(scope)
+	return defaultTokenCache.Clear(scope)
+}
+
+// CacheStats returns a snapshot of memCache's size and hit/miss/refresh
+// counters, for GET /api/cache.
+func CacheStats() tokencache.Stats {
+	return memCache.Stats()
+}
+
+// WriteCacheMetrics writes memCache's counters in Prometheus text
+// exposition format, for GET /metrics.
+func WriteCacheMetrics(w io.Writer) {
+	memCache.WriteMetrics(w)
+}
+
+// TokenInfo is what IntrospectToken knows about a cached token, for the
+// RFC 7662-shaped /oauth2/introspect endpoint.
+type TokenInfo struct {
+	Scope  string
+	Email  string
+	Expiry int64
+}
+
+// IntrospectToken looks up token among cached service tokens and reports
+// what it was issued for. ok is false if token isn't a cached token at all;
+// callers still need to compare Expiry against the current time to decide
+// "active".
+func IntrospectToken(token string) (TokenInfo, bool) {
+	scope, entry, ok := defaultTokenCache.findByToken(token)
+	if !ok {
+		return TokenInfo{}, false
+	}
+	return TokenInfo{Scope: scope, Email: entry.Email, Expiry: entry.Expiry}, true
+}
+
+// UserIdentity is the subset of auth.Response fields that describe the
+// signed-in person rather than a specific token, for the OIDC-shaped
+// /oauth2/userinfo endpoint.
+type UserIdentity struct {
+	Email     string
+	FirstName string
+	LastName  string
+	AccountID string
+}
+
+// UserInfo reports identity fields from the most recently fetched service
+// token for email. There's no dedicated userinfo call against Google's
+// endpoint — these fields just come along for free on every auth response,
+// so this reuses whatever GetServiceToken last cached.
+func UserInfo(email string) (UserIdentity, bool) {
+	entry, ok := defaultTokenCache.latestEntry(email)
+	if !ok {
+		return UserIdentity{}, false
+	}
+	return UserIdentity{
+		Email:     email,
+		FirstName: entry.FirstName,
+		LastName:  entry.LastName,
+		AccountID: entry.AccountID,
+	}, true
+}
@@ -81,32 +81,14 @@ func Checkin(cfg *config.Config) (*Result, error) {
 	}
 
 	// Decode protobuf response
-	decoded, err := proto.DecodeMessage(respBytes)
+	decoded, err := proto.Decode(respBytes, proto.CheckinResponseSchema)
 	if err != nil {
 		return nil, fmt.Errorf("decode response: %w", err)
 	}
 
-	result := &Result{}
-
-	// Field 7 = androidId (fixed64)
-	if v, ok := decoded["7"]; ok {
-		switch val := v.(type) {
-		case uint64:
-			result.AndroidID = val
-		case int64:
-			result.AndroidID = uint64(val)
-		}
-	}
-
-	// Field 8 = securityToken (fixed64)
-	if v, ok := decoded["8"]; ok {
-		switch val := v.(type) {
-		case uint64:
-			result.SecurityToken = val
-		case int64:
-			result.SecurityToken = uint64(val)
-		}
-	}
+	androidID, _ := decoded["7"].(uint64) // fixed64 on the wire
+	securityToken, _ := decoded["8"].(uint64)
+	result := &Result{AndroidID: androidID, SecurityToken: securityToken}
 
 	if result.AndroidID == 0 {
 		return nil, fmt.Errorf("checkin response missing androidId")
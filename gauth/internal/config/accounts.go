@@ -0,0 +1,172 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Account is one signed-in Google identity's persistent state: device
+// registration, the spoofed device profile, and the master token. Each
+// account keeps its own AndroidID/SecurityToken/Device because microG's
+// check-in protobuf issues a fresh identity per registration — reusing one
+// account's GSF ID for another account's requests gets them rejected as
+// spoofed.
+type Account struct {
+	Email         string `yaml:"email"`
+	AndroidID     string `yaml:"android_id"`
+	SecurityToken string `yaml:"security_token"`
+	MasterToken   string `yaml:"master_token"`
+
+	Device DeviceConfig `yaml:"device"`
+
+	// FCMRegistrations maps an app package to the registration token
+	// internal/mcs.Register returned for it, keyed the same way
+	// mtalk.Client dispatches (by category/appID) so a restart knows which
+	// app packages to resume an MCS subscription for without re-registering.
+	FCMRegistrations map[string]string `yaml:"fcm_registrations,omitempty"`
+
+	// SecretsEnc holds Email/MasterToken/SecurityToken encrypted, in place
+	// of the cleartext fields above, when a passphrase is configured — see
+	// secrets.go. nil means this account is stored in the pre-existing
+	// plaintext layout.
+	SecretsEnc *secretsEncBlob `yaml:"secrets_enc,omitempty"`
+}
+
+// AccountStore persists every account gauth has ever signed into, keyed by
+// email, plus which one subcommands use when --account/GAUTH_ACCOUNT isn't
+// given.
+type AccountStore struct {
+	Accounts map[string]*Account `yaml:"accounts"`
+	Default  string              `yaml:"default"`
+}
+
+const accountsFileName = "gauth_accounts.yaml"
+
+// AccountsPath returns the path to the account store, next to ConfigPath.
+func AccountsPath() string {
+	return filepath.Join(filepath.Dir(ConfigPath()), accountsFileName)
+}
+
+// LoadAccountStore reads the account store from AccountsPath, or returns an
+// empty one if it doesn't exist yet.
+func LoadAccountStore() *AccountStore {
+	return LoadAccountStoreFrom(AccountsPath())
+}
+
+// LoadAccountStoreFrom reads the account store from a specific path.
+func LoadAccountStoreFrom(path string) *AccountStore {
+	store := &AccountStore{Accounts: map[string]*Account{}}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return store
+	}
+	_ = yaml.Unmarshal(data, store)
+	if store.Accounts == nil {
+		store.Accounts = map[string]*Account{}
+	}
+	for email, acct := range store.Accounts {
+		unsealed := unsealAccount(*acct)
+		store.Accounts[email] = &unsealed
+	}
+	return store
+}
+
+// Save writes the account store to AccountsPath.
+func (s *AccountStore) Save() error {
+	return s.SaveTo(AccountsPath())
+}
+
+// SaveTo writes the account store to a specific path. Each account's
+// sensitive fields are sealed into SecretsEnc first when a passphrase is
+// configured (see secrets.go), and the write is atomic so an interrupted
+// save can't corrupt every signed-in account's credentials at once.
+func (s *AccountStore) SaveTo(path string) error {
+	sealed := &AccountStore{Accounts: make(map[string]*Account, len(s.Accounts)), Default: s.Default}
+	for email, acct := range s.Accounts {
+		sealedAcct, err := sealAccount(*acct)
+		if err != nil {
+			return fmt.Errorf("encrypt account %s: %w", email, err)
+		}
+		sealed.Accounts[email] = &sealedAcct
+	}
+
+	data, err := yaml.Marshal(sealed)
+	if err != nil {
+		return fmt.Errorf("marshal account store: %w", err)
+	}
+	return atomicWriteFile(path, data, 0600)
+}
+
+// Upsert saves (or replaces) acct, keyed by its Email, and marks it the
+// default if it's the first account the store has ever held.
+func (s *AccountStore) Upsert(acct *Account) {
+	if s.Accounts == nil {
+		s.Accounts = map[string]*Account{}
+	}
+	s.Accounts[acct.Email] = acct
+	if s.Default == "" {
+		s.Default = acct.Email
+	}
+}
+
+// SetDefault marks email as the account subcommands use when --account
+// isn't given. Returns an error if email isn't a known account.
+func (s *AccountStore) SetDefault(email string) error {
+	if _, ok := s.Accounts[email]; !ok {
+		return fmt.Errorf("no such account: %s", email)
+	}
+	s.Default = email
+	return nil
+}
+
+// Remove deletes email from the store. If it was the default, some other
+// remaining account (arbitrarily chosen) becomes the new default, or none if
+// the store is now empty.
+func (s *AccountStore) Remove(email string) {
+	delete(s.Accounts, email)
+	if s.Default != email {
+		return
+	}
+	s.Default = ""
+	for other := range s.Accounts {
+		s.Default = other
+		break
+	}
+}
+
+// Resolve returns the account to operate as: the explicit email if given,
+// else the store's default, else a fresh not-yet-logged-in Account for that
+// email (or entirely blank if there's no default either).
+func (s *AccountStore) Resolve(email string) *Account {
+	if email == "" {
+		email = s.Default
+	}
+	if acct, ok := s.Accounts[email]; ok {
+		return acct
+	}
+	return &Account{Email: email}
+}
+
+// ApplyAccount selects which persisted account c should operate as: the
+// explicit email (from --account or GAUTH_ACCOUNT) if given, else the
+// account store's default. It overwrites c's identity fields in place;
+// ServerPort/ProxyMode are untouched since those are server-level settings,
+// not per-account.
+func (c *Config) ApplyAccount(email string) {
+	store := LoadAccountStore()
+	c.Account = *store.Resolve(email)
+}
+
+// SaveAccount persists c's current identity fields into the account store,
+// keyed by Email, in addition to whatever Save persists to the main config
+// file. Call this after check-in or login so the per-account GSF ID/master
+// token survive switching to a different account and back.
+func (c *Config) SaveAccount() error {
+	store := LoadAccountStore()
+	acct := c.Account
+	store.Upsert(&acct)
+	return store.Save()
+}
@@ -9,21 +9,17 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-// Config holds all persistent state.
+// Config holds all persistent state. The identity fields (Account) describe
+// whichever account is currently selected — see accounts.go for the
+// multi-account store and ApplyAccount for how --account/GAUTH_ACCOUNT
+// switch between them.
 type Config struct {
-	// Device registration
-	AndroidID     string `yaml:"android_id"`
-	SecurityToken string `yaml:"security_token"`
-
-	// Account
-	Email       string `yaml:"email"`
-	MasterToken string `yaml:"master_token"`
-
-	// Device profile
-	Device DeviceConfig `yaml:"device"`
+	Account `yaml:",inline"`
 
 	// Server
-	ServerPort int `yaml:"server_port"`
+	ServerPort int    `yaml:"server_port"`
+	ProxyMode  string `yaml:"proxy_mode"` // "rewrite" (default) or "mitm"
+	APISecret  string `yaml:"api_secret"` // bearer token guarding /oauth2/token and /oauth2/introspect; unset disables the check
 }
 
 // DeviceConfig holds the spoofed device identity.
@@ -45,18 +41,20 @@ type DeviceConfig struct {
 func DefaultConfig() *Config {
 	return &Config{
 		ServerPort: 8080,
-		Device: DeviceConfig{
-			Model:        "Pixel 7",
-			Brand:        "google",
-			Manufacturer: "Google",
-			Device:       "panther",
-			Product:      "panther",
-			Hardware:     "tensor",
-			Fingerprint:  "google/panther/panther:13/TQ3A.230901.001/10750268:user/release-keys",
-			Bootloader:   "slider-1.2-9971768",
-			BuildID:      "TQ3A.230901.001",
-			SDKVersion:   33,
-			BuildTime:    1693440000,
+		Account: Account{
+			Device: DeviceConfig{
+				Model:        "Pixel 7",
+				Brand:        "google",
+				Manufacturer: "Google",
+				Device:       "panther",
+				Product:      "panther",
+				Hardware:     "tensor",
+				Fingerprint:  "google/panther/panther:13/TQ3A.230901.001/10750268:user/release-keys",
+				Bootloader:   "slider-1.2-9971768",
+				BuildID:      "TQ3A.230901.001",
+				SDKVersion:   33,
+				BuildTime:    1693440000,
+			},
 		},
 	}
 }
@@ -77,7 +75,10 @@ func Load() *Config {
 	return LoadFrom(configFileName)
 }
 
-// LoadFrom reads config from a specific path.
+// LoadFrom reads config from a specific path. If the Account it holds was
+// saved with encrypted secrets (secrets_enc), they're transparently
+// decrypted here — see secrets.go's unsealAccount for what happens when no
+// passphrase is configured or decryption fails.
 func LoadFrom(path string) *Config {
 	cfg := DefaultConfig()
 	data, err := os.ReadFile(path)
@@ -85,6 +86,7 @@ func LoadFrom(path string) *Config {
 		return cfg
 	}
 	_ = yaml.Unmarshal(data, cfg)
+	cfg.Account = unsealAccount(cfg.Account)
 	return cfg
 }
 
@@ -93,13 +95,55 @@ func (c *Config) Save() error {
 	return c.SaveTo(configFileName)
 }
 
-// SaveTo writes config to a specific path.
+// SaveTo writes config to a specific path. Email/MasterToken/SecurityToken
+// are sealed into secrets_enc first when a passphrase is configured (see
+// secrets.go), and the write is atomic so an interrupted save can't
+// corrupt the config file.
 func (c *Config) SaveTo(path string) error {
-	data, err := yaml.Marshal(c)
+	clone := *c
+	sealed, err := sealAccount(clone.Account)
+	if err != nil {
+		return fmt.Errorf("encrypt secrets: %w", err)
+	}
+	clone.Account = sealed
+
+	data, err := yaml.Marshal(&clone)
 	if err != nil {
 		return fmt.Errorf("marshal config: %w", err)
 	}
-	return os.WriteFile(path, data, 0600)
+	return atomicWriteFile(path, data, 0600)
+}
+
+// atomicWriteFile writes data to path by first writing to a temp file in
+// the same directory and renaming it into place, so a crash or power loss
+// mid-write leaves either the old file intact or the new one, never a
+// half-written one.
+func atomicWriteFile(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := os.CreateTemp(dir, ".gauth-tmp-*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op once the rename below succeeds
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temp file: %w", err)
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return fmt.Errorf("chmod temp file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("rename temp file: %w", err)
+	}
+	return nil
 }
 
 // HasRegistration returns true if device check-in has been done.
@@ -0,0 +1,318 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ProxyConfig controls how the proxy handlers pick their upstream and what
+// they're willing to touch. It replaces the string literals that used to be
+// hard-coded in googleProxyHandler/staticProxyHandler.
+type ProxyConfig struct {
+	UpstreamHost         string            `toml:"upstream_host"`
+	MountPath            string            `toml:"mount_path"`
+	StaticAllowlist      []string          `toml:"static_allowlist"`
+	StaticRewriteDomains []string          `toml:"static_rewrite_domains"`
+	StripResponseHeaders []string          `toml:"strip_response_headers"`
+	HostHeaderOverrides  map[string]string `toml:"host_header_overrides"`
+}
+
+// ServerTLSConfig controls whether and how the HTTP token server terminates
+// TLS. Mode "auto" generates (and caches) a self-signed localhost cert on
+// first run, "off" serves plain HTTP, and "custom" uses CertFile/KeyFile.
+type ServerTLSConfig struct {
+	Mode     string `toml:"tls"`
+	CertFile string `toml:"cert_file"`
+	KeyFile  string `toml:"key_file"`
+}
+
+// APIAuthConfig controls how server.Start's HTTP API is protected from
+// anyone who can reach the port: a list of bearer API keys, an mTLS client
+// CA (enforced at the TLS layer; see server.LoadServerTLSConfig), a
+// hashcash proof-of-work challenge gating /api/token specifically, and a
+// per-identity rate limit. None of these are mutually exclusive with TLS
+// mode; api_keys and hashcash are mutually exclusive with each other in
+// practice since server.authMiddleware only ever applies one.
+type APIAuthConfig struct {
+	APIKeys            []string `toml:"api_keys"`
+	ClientCA           string   `toml:"client_ca"`
+	HashcashEnabled    bool     `toml:"hashcash_enabled"`
+	HashcashDifficulty int      `toml:"hashcash_difficulty"`
+	RateLimitPerSecond float64  `toml:"rate_limit_per_second"`
+	RateLimitBurst     int      `toml:"rate_limit_burst"`
+}
+
+// Configured reports whether any access-control mode is set up — used to
+// decide whether server.Start may bind a non-loopback address.
+func (a APIAuthConfig) Configured() bool {
+	return len(a.APIKeys) > 0 || a.ClientCA != "" || a.HashcashEnabled
+}
+
+// fileConfig mirrors the on-disk TOML layout. Only the [proxy], [server],
+// and [auth] tables are layered for now; device/account fields stay on the
+// YAML Config struct and are loaded separately by Load/LoadFrom.
+type fileConfig struct {
+	Proxy  ProxyConfig     `toml:"proxy"`
+	Server ServerTLSConfig `toml:"server"`
+	Auth   APIAuthConfig   `toml:"auth"`
+}
+
+// DefaultServerTLSConfig returns the TLS defaults: generate-and-trust a
+// localhost cert automatically.
+func DefaultServerTLSConfig() ServerTLSConfig {
+	return ServerTLSConfig{Mode: "auto"}
+}
+
+// LoadServerTLSConfig composes the [server] TOML table with
+// GAUTH_SERVER_TLS_* environment overrides, same precedence as LoadLayered.
+func LoadServerTLSConfig(path string) ServerTLSConfig {
+	cfg := DefaultServerTLSConfig()
+
+	if path == "" {
+		path = defaultConfigTOMLPath()
+	}
+	var fc fileConfig
+	if _, err := toml.DecodeFile(path, &fc); err == nil && fc.Server.Mode != "" {
+		cfg = fc.Server
+	}
+
+	if v := os.Getenv("GAUTH_SERVER_TLS"); v != "" {
+		cfg.Mode = v
+	}
+	if v := os.Getenv("GAUTH_SERVER_TLS_CERT_FILE"); v != "" {
+		cfg.CertFile = v
+	}
+	if v := os.Getenv("GAUTH_SERVER_TLS_KEY_FILE"); v != "" {
+		cfg.KeyFile = v
+	}
+
+	return cfg
+}
+
+// DefaultAPIAuthConfig returns the auth defaults: no access control and no
+// rate limiting at all, matching this server's historical opt-in-only
+// security posture. HashcashDifficulty still defaults to a sane value since
+// it's meaningless (and harmless) until HashcashEnabled is also set.
+func DefaultAPIAuthConfig() APIAuthConfig {
+	return APIAuthConfig{HashcashDifficulty: 20}
+}
+
+// LoadAPIAuthConfig composes the [auth] TOML table with GAUTH_AUTH_*
+// environment overrides, same precedence as LoadServerTLSConfig.
+func LoadAPIAuthConfig(path string) APIAuthConfig {
+	cfg := DefaultAPIAuthConfig()
+
+	if path == "" {
+		path = defaultConfigTOMLPath()
+	}
+	var fc fileConfig
+	if _, err := toml.DecodeFile(path, &fc); err == nil {
+		if len(fc.Auth.APIKeys) > 0 {
+			cfg.APIKeys = fc.Auth.APIKeys
+		}
+		if fc.Auth.ClientCA != "" {
+			cfg.ClientCA = fc.Auth.ClientCA
+		}
+		if fc.Auth.HashcashEnabled {
+			cfg.HashcashEnabled = true
+		}
+		if fc.Auth.HashcashDifficulty > 0 {
+			cfg.HashcashDifficulty = fc.Auth.HashcashDifficulty
+		}
+		if fc.Auth.RateLimitPerSecond > 0 {
+			cfg.RateLimitPerSecond = fc.Auth.RateLimitPerSecond
+		}
+		if fc.Auth.RateLimitBurst > 0 {
+			cfg.RateLimitBurst = fc.Auth.RateLimitBurst
+		}
+	}
+
+	if v := os.Getenv("GAUTH_AUTH_API_KEYS"); v != "" {
+		cfg.APIKeys = splitEnvList(v)
+	}
+	if v := os.Getenv("GAUTH_AUTH_CLIENT_CA"); v != "" {
+		cfg.ClientCA = v
+	}
+	if v := os.Getenv("GAUTH_AUTH_HASHCASH"); v != "" {
+		cfg.HashcashEnabled = v == "1" || v == "true"
+	}
+	if v := os.Getenv("GAUTH_AUTH_HASHCASH_DIFFICULTY"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.HashcashDifficulty = n
+		}
+	}
+	if v := os.Getenv("GAUTH_AUTH_RATE_LIMIT_PER_SECOND"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			cfg.RateLimitPerSecond = f
+		}
+	}
+	if v := os.Getenv("GAUTH_AUTH_RATE_LIMIT_BURST"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			cfg.RateLimitBurst = n
+		}
+	}
+
+	return cfg
+}
+
+// DefaultProxyConfig returns the proxy defaults that used to be hard-coded
+// literals in server/proxy.go.
+func DefaultProxyConfig() ProxyConfig {
+	return ProxyConfig{
+		UpstreamHost: "accounts.google.com",
+		MountPath:    "/glogin",
+		StaticAllowlist: []string{
+			"ssl.gstatic.com",
+			"www.gstatic.com",
+			"fonts.gstatic.com",
+			"fonts.googleapis.com",
+			"apis.google.com",
+			"www.google.com",
+			"play.google.com",
+			"lh3.googleusercontent.com",
+			"accounts.youtube.com",
+			"myaccount.google.com",
+			"ogs.google.com",
+			"clients1.google.com",
+			"signaler-pa.clients6.google.com",
+			"content-autofill.googleapis.com",
+		},
+		StaticRewriteDomains: []string{
+			"ssl.gstatic.com",
+			"www.gstatic.com",
+			"fonts.gstatic.com",
+			"fonts.googleapis.com",
+			"apis.google.com",
+			"ogs.google.com",
+			"play.google.com",
+			"myaccount.google.com",
+			"lh3.googleusercontent.com",
+		},
+		StripResponseHeaders: []string{
+			"Content-Security-Policy",
+			"X-Frame-Options",
+			"Strict-Transport-Security",
+			"X-Content-Type-Options",
+		},
+		HostHeaderOverrides: map[string]string{},
+	}
+}
+
+// defaultConfigTOMLPath returns ~/.config/gauth/gauth.toml.
+func defaultConfigTOMLPath() string {
+	if dir, err := os.UserConfigDir(); err == nil {
+		return dir + "/gauth/gauth.toml"
+	}
+	return "gauth.toml"
+}
+
+// LoadLayered builds a ProxyConfig by composing, in increasing priority:
+// built-in defaults, the TOML file at path (or ~/.config/gauth/gauth.toml
+// when path is empty), GAUTH_-prefixed environment variables, and finally
+// command-line flags already parsed into overrides. Each layer only
+// overrides fields it actually sets, mirroring how oauth2_proxy composes
+// BurntSushi/toml config with go-options flag overrides.
+func LoadLayered(path string, overrides ProxyConfig) ProxyConfig {
+	cfg := DefaultProxyConfig()
+
+	if path == "" {
+		path = defaultConfigTOMLPath()
+	}
+	var fc fileConfig
+	if _, err := toml.DecodeFile(path, &fc); err == nil {
+		mergeProxyConfig(&cfg, fc.Proxy)
+	}
+
+	mergeProxyConfig(&cfg, proxyConfigFromEnv())
+	mergeProxyConfig(&cfg, overrides)
+
+	return cfg
+}
+
+// mergeProxyConfig overlays any non-zero fields of src onto dst.
+func mergeProxyConfig(dst *ProxyConfig, src ProxyConfig) {
+	if src.UpstreamHost != "" {
+		dst.UpstreamHost = src.UpstreamHost
+	}
+	if src.MountPath != "" {
+		dst.MountPath = src.MountPath
+	}
+	if len(src.StaticAllowlist) > 0 {
+		dst.StaticAllowlist = src.StaticAllowlist
+	}
+	if len(src.StaticRewriteDomains) > 0 {
+		dst.StaticRewriteDomains = src.StaticRewriteDomains
+	}
+	if len(src.StripResponseHeaders) > 0 {
+		dst.StripResponseHeaders = src.StripResponseHeaders
+	}
+	for host, override := range src.HostHeaderOverrides {
+		if dst.HostHeaderOverrides == nil {
+			dst.HostHeaderOverrides = map[string]string{}
+		}
+		dst.HostHeaderOverrides[host] = override
+	}
+}
+
+// proxyConfigFromEnv reads GAUTH_PROXY_* environment variables. List-valued
+// fields are comma-separated.
+func proxyConfigFromEnv() ProxyConfig {
+	var pc ProxyConfig
+	if v := os.Getenv("GAUTH_PROXY_UPSTREAM_HOST"); v != "" {
+		pc.UpstreamHost = v
+	}
+	if v := os.Getenv("GAUTH_PROXY_MOUNT_PATH"); v != "" {
+		pc.MountPath = v
+	}
+	if v := os.Getenv("GAUTH_PROXY_STATIC_ALLOWLIST"); v != "" {
+		pc.StaticAllowlist = splitEnvList(v)
+	}
+	if v := os.Getenv("GAUTH_PROXY_STATIC_REWRITE_DOMAINS"); v != "" {
+		pc.StaticRewriteDomains = splitEnvList(v)
+	}
+	if v := os.Getenv("GAUTH_PROXY_STRIP_RESPONSE_HEADERS"); v != "" {
+		pc.StripResponseHeaders = splitEnvList(v)
+	}
+	return pc
+}
+
+func splitEnvList(v string) []string {
+	parts := strings.Split(v, ",")
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// IsAllowedStaticDomain reports whether domain may be proxied under
+// /gproxy/, either because it's explicitly allowlisted or because it's a
+// subdomain of google.com/gstatic.com/googleapis.com/googleusercontent.com.
+func (p ProxyConfig) IsAllowedStaticDomain(domain string) bool {
+	for _, d := range p.StaticAllowlist {
+		if d == domain {
+			return true
+		}
+	}
+	for _, suffix := range []string{".google.com", ".gstatic.com", ".googleapis.com", ".googleusercontent.com"} {
+		if strings.HasSuffix(domain, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// HostHeaderFor returns the Host header to send upstream for domain,
+// honoring any configured override.
+func (p ProxyConfig) HostHeaderFor(domain string) string {
+	if override, ok := p.HostHeaderOverrides[domain]; ok {
+		return override
+	}
+	return domain
+}
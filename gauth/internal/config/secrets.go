@@ -0,0 +1,149 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	secretsEncVersion = 1
+	keyringService    = "gauth"
+	keyringUser       = "config-passphrase"
+)
+
+// secretsEncBlob is the on-disk encrypted form of an Account's sensitive
+// fields (Email, MasterToken, SecurityToken). Fields are exported only so
+// yaml.v3 can (de)serialize them.
+type secretsEncBlob struct {
+	Version    int    `yaml:"version"`
+	Salt       []byte `yaml:"salt"`
+	Nonce      []byte `yaml:"nonce"`
+	Ciphertext []byte `yaml:"ciphertext"`
+}
+
+// secretPayload is the plaintext JSON sealed inside a secretsEncBlob.
+type secretPayload struct {
+	Email         string `json:"email"`
+	MasterToken   string `json:"master_token"`
+	SecurityToken string `json:"security_token"`
+}
+
+// passphrase resolves the encryption passphrase: GAUTH_PASSPHRASE takes
+// precedence, then an OS-keyring-backed secret (via go-keyring) so a
+// passphrase can be configured once without living in the environment at
+// all. ok is false if neither is set, in which case callers fall back to
+// the pre-existing plaintext layout.
+func passphrase() (pass string, ok bool) {
+	if p := os.Getenv("GAUTH_PASSPHRASE"); p != "" {
+		return p, true
+	}
+	p, err := keyring.Get(keyringService, keyringUser)
+	if err != nil || p == "" {
+		return "", false
+	}
+	return p, true
+}
+
+// PassphraseConfigured reports whether Save/SaveTo would encrypt secrets
+// (a passphrase is available) or fall back to plaintext. `gauth config
+// rekey` uses this to tell the caller which outcome to expect.
+func PassphraseConfigured() bool {
+	_, ok := passphrase()
+	return ok
+}
+
+// deriveKey derives a chacha20poly1305 key from pass and salt via Argon2id,
+// using parameters recommended for interactive use (RFC 9106 ss. 4).
+func deriveKey(pass string, salt []byte) []byte {
+	return argon2.IDKey([]byte(pass), salt, 1, 64*1024, 4, chacha20poly1305.KeySize)
+}
+
+// sealAccount encrypts a's sensitive fields into a.SecretsEnc and blanks
+// them from the cleartext struct, when a passphrase is configured. Device
+// profile and FCMRegistrations aren't credentials and are left untouched;
+// AndroidID stays in cleartext too since it's a device identifier, not a
+// secret, and RefreshManager/doCheckin log it routinely. If no passphrase
+// is configured, a is returned unchanged — the existing plaintext layout.
+func sealAccount(a Account) (Account, error) {
+	pass, ok := passphrase()
+	if !ok {
+		return a, nil
+	}
+
+	payload := secretPayload{Email: a.Email, MasterToken: a.MasterToken, SecurityToken: a.SecurityToken}
+	plaintext, err := json.Marshal(payload)
+	if err != nil {
+		return a, fmt.Errorf("marshal secrets: %w", err)
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return a, fmt.Errorf("generate salt: %w", err)
+	}
+	aead, err := chacha20poly1305.NewX(deriveKey(pass, salt))
+	if err != nil {
+		return a, fmt.Errorf("init cipher: %w", err)
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return a, fmt.Errorf("generate nonce: %w", err)
+	}
+
+	a.SecretsEnc = &secretsEncBlob{
+		Version:    secretsEncVersion,
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: aead.Seal(nil, nonce, plaintext, nil),
+	}
+	a.Email, a.MasterToken, a.SecurityToken = "", "", ""
+	return a, nil
+}
+
+// unsealAccount decrypts a.SecretsEnc back into a's cleartext fields, when
+// present and a passphrase is configured. A missing passphrase or a failed
+// decryption (wrong passphrase, corrupted blob, unsupported version) is
+// logged and leaves a's sensitive fields blank rather than returning an
+// error, the same "degrade gracefully" precedent TokenCache.load uses for a
+// corrupted cache file — so Load/LoadFrom don't need an error return that
+// would cascade across every caller.
+func unsealAccount(a Account) Account {
+	blob := a.SecretsEnc
+	if blob == nil {
+		return a
+	}
+	pass, ok := passphrase()
+	if !ok {
+		log.Printf("[config] %s has encrypted secrets but no passphrase is configured (set GAUTH_PASSPHRASE or store one in the OS keyring); leaving credentials blank", configFileName)
+		return a
+	}
+	if blob.Version != secretsEncVersion {
+		log.Printf("[config] secrets_enc version %d is not supported; leaving credentials blank", blob.Version)
+		return a
+	}
+
+	aead, err := chacha20poly1305.NewX(deriveKey(pass, blob.Salt))
+	if err != nil {
+		log.Printf("[config] init cipher for secrets_enc: %v; leaving credentials blank", err)
+		return a
+	}
+	plaintext, err := aead.Open(nil, blob.Nonce, blob.Ciphertext, nil)
+	if err != nil {
+		log.Printf("[config] decrypt secrets_enc (wrong passphrase?): %v; leaving credentials blank", err)
+		return a
+	}
+	var payload secretPayload
+	if err := json.Unmarshal(plaintext, &payload); err != nil {
+		log.Printf("[config] unmarshal decrypted secrets_enc: %v; leaving credentials blank", err)
+		return a
+	}
+
+	a.Email, a.MasterToken, a.SecurityToken = payload.Email, payload.MasterToken, payload.SecurityToken
+	return a
+}
@@ -163,6 +163,91 @@ console.log('[gauth] Android WebView bridge injected successfully');
 	)
 }
 
+// webAuthnShimJS intercepts navigator.credentials.create/get (the calls
+// Google's EmbeddedSetup page makes for passkey/2-Step-Verification
+// challenges) and forwards them over the webview bridge to
+// __gauthWebAuthnCreate/__gauthWebAuthnGet, which webview.RunWebViewLogin
+// binds to a real webauthn.Authenticator. WebView2/WebKitGTK have no
+// platform authenticator and no USB access from JS, so without this the
+// page's navigator.credentials calls just reject and passkey login dead-ends.
+const webAuthnShimJS = `
+(function() {
+    function b64url(buf) {
+        var bytes = new Uint8Array(buf);
+        var str = '';
+        for (var i = 0; i < bytes.length; i++) str += String.fromCharCode(bytes[i]);
+        return btoa(str).replace(/\+/g, '-').replace(/\//g, '_').replace(/=+$/, '');
+    }
+    function unb64url(s) {
+        s = s.replace(/-/g, '+').replace(/_/g, '/');
+        while (s.length % 4) s += '=';
+        var str = atob(s);
+        var bytes = new Uint8Array(str.length);
+        for (var i = 0; i < str.length; i++) bytes[i] = str.charCodeAt(i);
+        return bytes.buffer;
+    }
+
+    navigator.credentials = navigator.credentials || {};
+
+    navigator.credentials.create = function(options) {
+        var pk = options.publicKey;
+        var req = {
+            rpId: pk.rp.id,
+            rpName: pk.rp.name,
+            userId: b64url(pk.user.id),
+            userName: pk.user.name,
+            challenge: b64url(pk.challenge)
+        };
+        return window.__gauthWebAuthnCreate(JSON.stringify(req)).then(function(respJSON) {
+            var resp = JSON.parse(respJSON);
+            return {
+                id: resp.id,
+                rawId: unb64url(resp.rawId),
+                type: 'public-key',
+                response: {
+                    attestationObject: unb64url(resp.attestationObject),
+                    clientDataJSON: unb64url(resp.clientDataJSON)
+                },
+                getClientExtensionResults: function() { return {}; }
+            };
+        });
+    };
+
+    navigator.credentials.get = function(options) {
+        var pk = options.publicKey;
+        var req = {
+            rpId: pk.rpId,
+            challenge: b64url(pk.challenge),
+            allowCredentials: (pk.allowCredentials || []).map(function(c) { return b64url(c.id); })
+        };
+        return window.__gauthWebAuthnGet(JSON.stringify(req)).then(function(respJSON) {
+            var resp = JSON.parse(respJSON);
+            return {
+                id: resp.id,
+                rawId: unb64url(resp.rawId),
+                type: 'public-key',
+                response: {
+                    authenticatorData: unb64url(resp.authenticatorData),
+                    signature: unb64url(resp.signature),
+                    userHandle: resp.userHandle ? unb64url(resp.userHandle) : null,
+                    clientDataJSON: unb64url(resp.clientDataJSON)
+                },
+                getClientExtensionResults: function() { return {}; }
+            };
+        });
+    };
+
+    console.log('[gauth] WebAuthn passthrough shim installed');
+})();
+`
+
+// BuildWebAuthnShim returns the JS that redirects navigator.credentials
+// calls to the Go-side webauthn.Authenticator over the bridge. Injected
+// alongside BuildJSBridge via w.Init().
+func BuildWebAuthnShim() string {
+	return webAuthnShimJS
+}
+
 // ExtractOAuthToken parses the oauth_token from a cookie string.
 func ExtractOAuthToken(cookies string) string {
 	for _, cookie := range strings.Split(cookies, ";") {
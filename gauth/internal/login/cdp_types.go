@@ -0,0 +1,30 @@
+package login
+
+// cdpCookie and cdpCookieResponse mirror the Chrome DevTools Protocol's
+// Network.getAllCookies result, shared between the Windows-only in-process
+// CDP call in cookies.go (via WebView2's CallDevToolsProtocolMethod) and
+// the cross-platform websocket CDP client in chrometransport.go (talking to
+// a separate Chrome/Chromium process) — same protocol, two transports.
+type cdpCookieResponse struct {
+	Cookies []cdpCookie `json:"cookies"`
+}
+
+type cdpCookie struct {
+	Name     string  `json:"name"`
+	Value    string  `json:"value"`
+	Domain   string  `json:"domain"`
+	Path     string  `json:"path"`
+	HTTPOnly bool    `json:"httpOnly"`
+	Secure   bool    `json:"secure"`
+	Expires  float64 `json:"expires"`
+}
+
+// findOAuthCookie returns the oauth_token cookie's value, if present.
+func findOAuthCookie(cookies []cdpCookie) (string, bool) {
+	for _, c := range cookies {
+		if c.Name == "oauth_token" {
+			return c.Value, true
+		}
+	}
+	return "", false
+}
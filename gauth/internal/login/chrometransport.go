@@ -0,0 +1,234 @@
+package login
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Transport abstracts the CDP backend RunHeadlessLogin drives. ChromeTransport
+// is the default (a locally-running Chrome/Chromium with
+// --remote-debugging-port), but anything that can navigate, evaluate JS, run
+// a script on every new document, and read cookies can implement this —
+// e.g. a remote browser farm reached over its own tunnel.
+type Transport interface {
+	// Navigate loads url in the transport's tab.
+	Navigate(url string) error
+	// AddScriptOnNewDocument installs js to run before every page script,
+	// the CDP equivalent of webview2's w.Init.
+	AddScriptOnNewDocument(js string) error
+	// Eval runs js in the tab and returns its JSON-encoded result.
+	Eval(js string) (json.RawMessage, error)
+	// Cookies returns every cookie visible to the tab, including HttpOnly
+	// ones JS can't read itself.
+	Cookies() ([]cdpCookie, error)
+	// Close releases the transport's resources (not the browser process
+	// itself, which ChromeTransport doesn't own).
+	Close() error
+}
+
+// ChromeTransport drives a tab in an already-running Chrome/Chromium
+// instance over its DevTools Protocol websocket. It does not launch
+// Chrome itself — callers are expected to start it with
+// `--headless=new --remote-debugging-port=<port>` (or use their normal
+// browser with remote debugging enabled), the same "bring your own
+// browser" model gauth already uses for RunLoopbackLogin's system-default
+// browser.
+type ChromeTransport struct {
+	ws     *wsConn
+	nextID atomic.Int64
+
+	mu      sync.Mutex
+	pending map[int64]chan cdpResult
+}
+
+type cdpResult struct {
+	result json.RawMessage
+	err    error
+}
+
+type cdpTarget struct {
+	ID                   string `json:"id"`
+	WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+}
+
+// NewChromeTransport opens a new tab on the Chrome instance listening on
+// remoteDebuggingPort and connects to its CDP websocket.
+func NewChromeTransport(remoteDebuggingPort int) (*ChromeTransport, error) {
+	newTabURL := fmt.Sprintf("http://127.0.0.1:%d/json/new", remoteDebuggingPort)
+	resp, err := http.Get(newTabURL)
+	if err != nil {
+		return nil, fmt.Errorf("open new tab (is Chrome running with --remote-debugging-port=%d?): %w", remoteDebuggingPort, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read new-tab response: %w", err)
+	}
+
+	var target cdpTarget
+	if err := json.Unmarshal(body, &target); err != nil {
+		return nil, fmt.Errorf("parse new-tab response: %w", err)
+	}
+	if target.WebSocketDebuggerURL == "" {
+		return nil, fmt.Errorf("new-tab response had no webSocketDebuggerUrl")
+	}
+
+	ws, err := dialWebSocket(target.WebSocketDebuggerURL)
+	if err != nil {
+		return nil, fmt.Errorf("connect to tab websocket: %w", err)
+	}
+
+	t := &ChromeTransport{ws: ws, pending: make(map[int64]chan cdpResult)}
+	go t.readLoop()
+
+	if _, err := t.call("Page.enable", nil); err != nil {
+		t.Close()
+		return nil, fmt.Errorf("enable Page domain: %w", err)
+	}
+	if _, err := t.call("Network.enable", nil); err != nil {
+		t.Close()
+		return nil, fmt.Errorf("enable Network domain: %w", err)
+	}
+	if _, err := t.call("Runtime.enable", nil); err != nil {
+		t.Close()
+		return nil, fmt.Errorf("enable Runtime domain: %w", err)
+	}
+
+	return t, nil
+}
+
+// readLoop demultiplexes CDP responses onto the waiting call()'s channel by
+// id; it silently drops unsolicited events, since RunHeadlessLogin polls
+// state instead of subscribing to them.
+func (t *ChromeTransport) readLoop() {
+	for {
+		raw, err := t.ws.readText()
+		if err != nil {
+			t.mu.Lock()
+			for id, ch := range t.pending {
+				ch <- cdpResult{err: fmt.Errorf("websocket closed: %w", err)}
+				delete(t.pending, id)
+			}
+			t.mu.Unlock()
+			return
+		}
+
+		var msg struct {
+			ID     int64           `json:"id"`
+			Result json.RawMessage `json:"result"`
+			Error  *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			continue
+		}
+		if msg.ID == 0 {
+			continue // CDP event, not a call response
+		}
+
+		t.mu.Lock()
+		ch, ok := t.pending[msg.ID]
+		if ok {
+			delete(t.pending, msg.ID)
+		}
+		t.mu.Unlock()
+		if !ok {
+			continue
+		}
+
+		if msg.Error != nil {
+			ch <- cdpResult{err: fmt.Errorf("cdp error: %s", msg.Error.Message)}
+		} else {
+			ch <- cdpResult{result: msg.Result}
+		}
+	}
+}
+
+// call sends one CDP command and blocks for its response.
+func (t *ChromeTransport) call(method string, params interface{}) (json.RawMessage, error) {
+	id := t.nextID.Add(1)
+	ch := make(chan cdpResult, 1)
+
+	t.mu.Lock()
+	t.pending[id] = ch
+	t.mu.Unlock()
+
+	req := struct {
+		ID     int64       `json:"id"`
+		Method string      `json:"method"`
+		Params interface{} `json:"params,omitempty"`
+	}{id, method, params}
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("encode cdp request: %w", err)
+	}
+	if err := t.ws.writeText(payload); err != nil {
+		return nil, fmt.Errorf("send cdp request: %w", err)
+	}
+
+	res := <-ch
+	return res.result, res.err
+}
+
+// Navigate implements Transport.
+func (t *ChromeTransport) Navigate(url string) error {
+	_, err := t.call("Page.navigate", map[string]string{"url": url})
+	return err
+}
+
+// AddScriptOnNewDocument implements Transport.
+func (t *ChromeTransport) AddScriptOnNewDocument(js string) error {
+	_, err := t.call("Page.addScriptToEvaluateOnNewDocument", map[string]string{"source": js})
+	return err
+}
+
+// Eval implements Transport.
+func (t *ChromeTransport) Eval(js string) (json.RawMessage, error) {
+	result, err := t.call("Runtime.evaluate", map[string]interface{}{
+		"expression":    js,
+		"returnByValue": true,
+		"awaitPromise":  true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var evalResult struct {
+		Result struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"result"`
+		ExceptionDetails *struct {
+			Text string `json:"text"`
+		} `json:"exceptionDetails"`
+	}
+	if err := json.Unmarshal(result, &evalResult); err != nil {
+		return nil, fmt.Errorf("parse eval result: %w", err)
+	}
+	if evalResult.ExceptionDetails != nil {
+		return nil, fmt.Errorf("js exception: %s", evalResult.ExceptionDetails.Text)
+	}
+	return evalResult.Result.Value, nil
+}
+
+// Cookies implements Transport.
+func (t *ChromeTransport) Cookies() ([]cdpCookie, error) {
+	result, err := t.call("Network.getAllCookies", nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp cdpCookieResponse
+	if err := json.Unmarshal(result, &resp); err != nil {
+		return nil, fmt.Errorf("parse cookies response: %w", err)
+	}
+	return resp.Cookies, nil
+}
+
+// Close implements Transport.
+func (t *ChromeTransport) Close() error {
+	return t.ws.Close()
+}
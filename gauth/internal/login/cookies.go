@@ -1,3 +1,5 @@
+//go:build windows
+
 // Package login implements a cookie manager for WebView2 using the
 // Chrome DevTools Protocol (CDP) via the ICoreWebView2 COM vtable.
 // This reads HttpOnly cookies that JavaScript's document.cookie cannot access.
@@ -18,21 +20,6 @@ import (
 	"golang.org/x/sys/windows"
 )
 
-// cdpCookieResponse is the response from Network.getAllCookies
-type cdpCookieResponse struct {
-	Cookies []cdpCookie `json:"cookies"`
-}
-
-type cdpCookie struct {
-	Name     string  `json:"name"`
-	Value    string  `json:"value"`
-	Domain   string  `json:"domain"`
-	Path     string  `json:"path"`
-	HTTPOnly bool    `json:"httpOnly"`
-	Secure   bool    `json:"secure"`
-	Expires  float64 `json:"expires"`
-}
-
 // tokenCallback is called when the oauth_token is found
 type tokenCallback func(token string)
 
@@ -182,14 +169,12 @@ func cdpInvoke(this uintptr, errorCode uintptr, resultJSON uintptr) uintptr {
 
 	log.Printf("[gauth] CDP: got %d cookies", len(resp.Cookies))
 
-	for _, c := range resp.Cookies {
-		if c.Name == "oauth_token" {
-			log.Printf("[gauth] ðŸŽ¯ Found oauth_token! (httpOnly=%v, len=%d)", c.HTTPOnly, len(c.Value))
-			if cb != nil {
-				cb(c.Value)
-			}
-			return 0
+	if token, ok := findOAuthCookie(resp.Cookies); ok {
+		log.Printf("[gauth] 🎯 Found oauth_token! (len=%d)", len(token))
+		if cb != nil {
+			cb(token)
 		}
+		return 0
 	}
 
 	log.Printf("[gauth] oauth_token not found yet (%d cookies checked)", len(resp.Cookies))
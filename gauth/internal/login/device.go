@@ -0,0 +1,175 @@
+// Package login — device-code sign-in (RFC 8628 OAuth 2.0 Device
+// Authorization Grant). Unlike RunWebViewLogin this needs no WebView2/CDP and
+// works on any platform, at the cost of a standard Google consent screen
+// instead of the spoofed-Android embedded setup flow. The id_token it yields
+// is handed back as a LoginResult.OAuthToken so callers can feed it into the
+// same auth.ExchangeOAuthForMaster path as the WebView flow.
+package login
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nicksrandall/gauth/internal/config"
+)
+
+const (
+	deviceCodeURL  = "https://oauth2.googleapis.com/device/code"
+	deviceTokenURL = "https://oauth2.googleapis.com/token"
+
+	// deviceFlowClientID/Secret are Google's own public "installed app" OAuth
+	// client, the same one a number of unofficial Google CLIs (gcloud,
+	// rclone) use for device-code login — there's no gauth-specific client
+	// registered with Google.
+	deviceFlowClientID     = "32555940559.apps.googleusercontent.com"
+	deviceFlowClientSecret = "ZmssLNjJy2998hD4CTg2ejr2"
+	deviceFlowScope        = "email profile openid https://www.googleapis.com/auth/userinfo.email"
+
+	deviceFlowDefaultInterval = 5 * time.Second
+	deviceFlowSlowDownStep    = 5 * time.Second
+)
+
+// deviceCodeResponse is the JSON body returned by deviceCodeURL.
+type deviceCodeResponse struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURL string `json:"verification_url"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+// deviceTokenResponse is the JSON body returned by deviceTokenURL, both on
+// success and for the "keep polling" error states RFC 8628 defines.
+type deviceTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	IDToken     string `json:"id_token"`
+	Error       string `json:"error"`
+}
+
+// RunDeviceFlowLogin implements the OAuth 2.0 Device Authorization Grant: it
+// requests a device/user code pair, prints the verification URL and code for
+// the user to enter in any browser (including on another machine), then
+// polls until they finish (or the code expires).
+func RunDeviceFlowLogin(cfg *config.Config) (*LoginResult, error) {
+	client := &http.Client{Timeout: 15 * time.Second}
+
+	codeResp, err := requestDeviceCode(cfg, client)
+	if err != nil {
+		return nil, fmt.Errorf("request device code: %w", err)
+	}
+
+	fmt.Println()
+	fmt.Printf("To sign in, go to: %s\n", codeResp.VerificationURL)
+	fmt.Printf("And enter code:    %s\n\n", codeResp.UserCode)
+	fmt.Println("Waiting for sign-in to complete...")
+
+	token, err := pollForDeviceToken(cfg, client, codeResp)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{OAuthToken: token}, nil
+}
+
+func requestDeviceCode(cfg *config.Config, client *http.Client) (*deviceCodeResponse, error) {
+	form := url.Values{
+		"client_id": {deviceFlowClientID},
+		"scope":     {deviceFlowScope},
+	}
+	req, err := http.NewRequest("POST", deviceCodeURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", cfg.AuthUserAgent())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out deviceCodeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || out.DeviceCode == "" {
+		return nil, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, deviceCodeURL)
+	}
+	return &out, nil
+}
+
+// pollForDeviceToken polls deviceTokenURL at codeResp's interval (adjusted
+// on slow_down) until a token comes back, the user denies access, or the
+// overall deadline derived from expires_in passes.
+func pollForDeviceToken(cfg *config.Config, client *http.Client, codeResp *deviceCodeResponse) (string, error) {
+	interval := deviceFlowDefaultInterval
+	if codeResp.Interval > 0 {
+		interval = time.Duration(codeResp.Interval) * time.Second
+	}
+	deadline := time.Now().Add(time.Duration(codeResp.ExpiresIn) * time.Second)
+
+	for {
+		time.Sleep(interval)
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("device code expired before sign-in was completed")
+		}
+
+		out, err := requestDeviceToken(cfg, client, codeResp.DeviceCode)
+		if err != nil {
+			return "", err
+		}
+
+		switch out.Error {
+		case "":
+			token := out.IDToken
+			if token == "" {
+				token = out.AccessToken
+			}
+			if token == "" {
+				return "", fmt.Errorf("empty token in device flow response")
+			}
+			return token, nil
+		case "authorization_pending":
+			// Keep polling at the same interval.
+		case "slow_down":
+			interval += deviceFlowSlowDownStep
+		case "access_denied":
+			return "", fmt.Errorf("sign-in was denied")
+		case "expired_token":
+			return "", fmt.Errorf("device code expired before sign-in was completed")
+		default:
+			return "", fmt.Errorf("device flow error: %s", out.Error)
+		}
+	}
+}
+
+func requestDeviceToken(cfg *config.Config, client *http.Client, deviceCode string) (*deviceTokenResponse, error) {
+	form := url.Values{
+		"client_id":     {deviceFlowClientID},
+		"client_secret": {deviceFlowClientSecret},
+		"device_code":   {deviceCode},
+		"grant_type":    {"urn:ietf:params:oauth:grant-type:device_code"},
+	}
+	req, err := http.NewRequest("POST", deviceTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", cfg.AuthUserAgent())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("poll token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var out deviceTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("decode token response: %w", err)
+	}
+	return &out, nil
+}
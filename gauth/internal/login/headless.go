@@ -0,0 +1,165 @@
+package login
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nicksrandall/gauth/internal/config"
+)
+
+// Credentials is the email/password RunHeadlessLogin scripts into Google's
+// sign-in form. There's no interactive fallback here — unlike
+// RunWebViewLogin/RunLoopbackLogin, which show the user a real page to
+// type into — so headless mode only covers the plain-password path;
+// accounts behind 2-Step Verification or a CAPTCHA challenge will time out
+// waiting for a password field that never appears and need one of the
+// interactive providers instead.
+type Credentials struct {
+	Email    string
+	Password string
+}
+
+// defaultRemoteDebuggingPort matches Chrome/Chromium's own
+// --remote-debugging-port default, so `chrome --headless=new` with no
+// extra flags just works.
+const defaultRemoteDebuggingPort = 9222
+
+// HeadlessProvider is the Provider for CI/server use: it drives an
+// already-running Chrome/Chromium instance over CDP instead of opening any
+// window. Unlike WebView2Provider/LoopbackProvider it needs Credentials
+// up front, since there's no window for the user to type into.
+type HeadlessProvider struct {
+	Credentials Credentials
+	// RemoteDebuggingPort is the port Chrome's --remote-debugging-port is
+	// listening on; 0 uses defaultRemoteDebuggingPort.
+	RemoteDebuggingPort int
+}
+
+// Start implements Provider.
+func (p HeadlessProvider) Start(cfg *config.Config) (*LoginResult, error) {
+	port := p.RemoteDebuggingPort
+	if port == 0 {
+		port = defaultRemoteDebuggingPort
+	}
+	transport, err := NewChromeTransport(port)
+	if err != nil {
+		return nil, err
+	}
+	defer transport.Close()
+	return RunHeadlessLogin(cfg, p.Credentials, transport)
+}
+
+// RunHeadlessLogin drives transport through Google's EmbeddedSetup sign-in
+// page purely via CDP — no visible window, no WebView2 dependency — and
+// returns once it has captured an oauth_token cookie. transport is usually
+// a *ChromeTransport, but any Transport works, so tests or alternate
+// browser backends can supply their own.
+func RunHeadlessLogin(cfg *config.Config, creds Credentials, transport Transport) (*LoginResult, error) {
+	result := &LoginResult{}
+
+	// Installed before Page.navigate so it's in place for the very first
+	// script on the page, the CDP equivalent of webview2's w.Init ordering.
+	if err := transport.AddScriptOnNewDocument(BuildJSBridge(cfg)); err != nil {
+		return nil, fmt.Errorf("install js bridge: %w", err)
+	}
+
+	loginURL := BuildLoginURL()
+	log.Printf("[gauth] Navigating headless tab to: %s", loginURL)
+	if err := transport.Navigate(loginURL); err != nil {
+		return nil, fmt.Errorf("navigate: %w", err)
+	}
+
+	// Selectors below match Google's sign-in page as of this writing;
+	// like the WebView2 "Extract Token" button and the mm bridge itself,
+	// they're tied to Google's current markup and may need updating if
+	// Google changes it.
+	if err := waitForSelector(transport, `input[type="email"]`, 30*time.Second); err != nil {
+		return nil, fmt.Errorf("wait for email field: %w", err)
+	}
+	if err := fillAndClick(transport, `input[type="email"]`, creds.Email, "#identifierNext"); err != nil {
+		return nil, fmt.Errorf("submit email: %w", err)
+	}
+
+	if err := waitForSelector(transport, `input[type="password"]`, 30*time.Second); err != nil {
+		return nil, fmt.Errorf("wait for password field: %w", err)
+	}
+	if err := fillAndClick(transport, `input[type="password"]`, creds.Password, "#passwordNext"); err != nil {
+		return nil, fmt.Errorf("submit password: %w", err)
+	}
+
+	token, err := pollForOAuthCookie(transport, 2*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
+	result.OAuthToken = token
+	log.Printf("[gauth] ✅ OAuth token received (length: %d)", len(token))
+	return result, nil
+}
+
+// waitForSelector polls document.querySelector(selector) until it resolves
+// to a non-null element or timeout elapses.
+func waitForSelector(transport Transport, selector string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	js := fmt.Sprintf(`!!document.querySelector(%q)`, selector)
+	for time.Now().Before(deadline) {
+		raw, err := transport.Eval(js)
+		if err != nil {
+			return err
+		}
+		var found bool
+		if err := json.Unmarshal(raw, &found); err == nil && found {
+			return nil
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return fmt.Errorf("timed out waiting for %s", selector)
+}
+
+// fillAndClick sets inputSelector's value (via the native property setter,
+// so frameworks relying on input events still notice the change) and
+// clicks nextSelector.
+func fillAndClick(transport Transport, inputSelector, value, nextSelector string) error {
+	js := fmt.Sprintf(`(function() {
+		var el = document.querySelector(%q);
+		if (!el) return false;
+		var setter = Object.getOwnPropertyDescriptor(window.HTMLInputElement.prototype, 'value').set;
+		setter.call(el, %q);
+		el.dispatchEvent(new Event('input', { bubbles: true }));
+		var next = document.querySelector(%q);
+		if (next) next.click();
+		return true;
+	})()`, inputSelector, value, nextSelector)
+
+	raw, err := transport.Eval(js)
+	if err != nil {
+		return err
+	}
+	var ok bool
+	if err := json.Unmarshal(raw, &ok); err != nil {
+		return fmt.Errorf("parse fillAndClick result: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("element %s not found", inputSelector)
+	}
+	return nil
+}
+
+// pollForOAuthCookie polls transport.Cookies() until oauth_token appears
+// or timeout elapses.
+func pollForOAuthCookie(transport Transport, timeout time.Duration) (string, error) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		cookies, err := transport.Cookies()
+		if err != nil {
+			return "", fmt.Errorf("read cookies: %w", err)
+		}
+		if token, ok := findOAuthCookie(cookies); ok {
+			return token, nil
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return "", fmt.Errorf("timed out waiting for oauth_token cookie (2FA/captcha challenge?)")
+}
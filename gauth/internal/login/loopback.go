@@ -0,0 +1,136 @@
+// Package login — browser-based sign-in via a loopback HTTP redirect, for
+// platforms without WebView2 (Linux/macOS). Unlike RunWebViewLogin this
+// opens the user's real default browser rather than an embedded webview, so
+// there's no JS bridge we control on the page itself: the oauth_token is
+// captured either from the redirect_uri query string (if EmbeddedSetup
+// honors it) or, failing that, by having the user paste a one-line snippet
+// into the browser console that posts document.cookie back to us.
+package login
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/nicksrandall/gauth/internal/config"
+)
+
+// LoopbackProvider is the Provider for platforms without WebView2: it opens
+// the system browser and waits for the oauth_token on a loopback server.
+type LoopbackProvider struct{}
+
+// Start implements Provider.
+func (LoopbackProvider) Start(cfg *config.Config) (*LoginResult, error) {
+	return RunLoopbackLogin(cfg)
+}
+
+// RunLoopbackLogin opens the system browser to Google's EmbeddedSetup page
+// with redirect_uri pointing at a short-lived 127.0.0.1 server, and returns
+// once that server has captured an oauth_token.
+func RunLoopbackLogin(cfg *config.Config) (*LoginResult, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("listen on loopback: %w", err)
+	}
+	defer ln.Close()
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	loginURL := BuildLoginURL() + fmt.Sprintf("&redirect_uri=http://127.0.0.1:%d/callback", port)
+
+	result := &LoginResult{}
+	done := make(chan struct{})
+	var once sync.Once
+	finish := func(token string) {
+		once.Do(func() {
+			result.OAuthToken = token
+			close(done)
+		})
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if token := r.URL.Query().Get("oauth_token"); token != "" {
+			finish(token)
+			fmt.Fprint(w, loopbackSuccessHTML)
+			return
+		}
+		fmt.Fprintf(w, loopbackBookmarkletHTML, port)
+	})
+	mux.HandleFunc("/capture", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Cookies string `json:"cookies"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		token := ExtractOAuthToken(body.Cookies)
+		if token == "" {
+			http.Error(w, "no oauth_token cookie found", http.StatusBadRequest)
+			return
+		}
+		finish(token)
+		fmt.Fprint(w, "ok")
+	})
+
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	log.Printf("[gauth] Opening browser for sign-in: %s", loginURL)
+	if err := openBrowser(loginURL); err != nil {
+		log.Printf("[gauth] Couldn't open browser automatically (%v); open this URL manually:", err)
+		fmt.Println(loginURL)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Minute):
+		once.Do(func() { result.Error = fmt.Errorf("login timeout (5 minutes)") })
+	}
+
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.OAuthToken == "" {
+		return nil, fmt.Errorf("no oauth_token received; login may have failed")
+	}
+
+	log.Printf("[gauth] ✅ OAuth token received (length: %d)", len(result.OAuthToken))
+	return result, nil
+}
+
+// openBrowser shells out to the platform's "open a URL" command.
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
+
+const loopbackBookmarkletHTML = `<!DOCTYPE html>
+<html><body style="font-family: sans-serif; max-width: 640px; margin: 4rem auto;">
+<h2>Finish signing in to gauth</h2>
+<p>Google didn't redirect back with a token automatically. Open developer
+tools on the Google sign-in tab, paste the following into the console, and
+press Enter:</p>
+<pre style="background:#f0f0f0; padding:1rem; overflow-x:auto;">fetch('http://127.0.0.1:%d/capture',{method:'POST',headers:{'Content-Type':'application/json'},body:JSON.stringify({cookies:document.cookie})}).then(()=>document.body.innerHTML='&lt;h2&gt;Done — you can close this tab.&lt;/h2&gt;')</pre>
+</body></html>`
+
+const loopbackSuccessHTML = `<!DOCTYPE html>
+<html><body style="font-family: sans-serif; max-width: 640px; margin: 4rem auto;">
+<h2>Signed in — you can close this tab.</h2>
+</body></html>`
@@ -0,0 +1,19 @@
+package login
+
+import "github.com/nicksrandall/gauth/internal/config"
+
+// LoginResult contains the login outcome.
+type LoginResult struct {
+	OAuthToken string
+	Cancelled  bool
+	Error      error
+}
+
+// Provider signs the user into Google and returns an oauth_token suitable
+// for auth.ExchangeOAuthForMaster. Each implementation gets there a
+// different way — see WebView2Provider (Windows-only, spoofed Android
+// WebView) and LoopbackProvider (any platform, real browser + loopback
+// redirect) — but they're interchangeable from cmdLogin's point of view.
+type Provider interface {
+	Start(cfg *config.Config) (*LoginResult, error)
+}
@@ -0,0 +1,196 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// aaguid identifies this authenticator model. All-zero is the conventional
+// value for "no particular model", which is what software authenticators
+// without a vendor AAGUID registration use.
+var aaguid = make([]byte, 16)
+
+// clientDataJSON builds the JSON WebAuthn signs over, matching what
+// navigator.credentials would normally construct in-browser before
+// handing it to a real authenticator.
+func clientDataJSON(typ, challenge, origin string) ([]byte, error) {
+	return json.Marshal(struct {
+		Type      string `json:"type"`
+		Challenge string `json:"challenge"`
+		Origin    string `json:"origin"`
+	}{typ, challenge, origin})
+}
+
+// Create implements Authenticator.Create: it mints a new P-256 credential
+// for opts.RPID, stores it, and returns a "none"-format attestation
+// (authenticatorData + empty attestation statement), which is what a real
+// platform authenticator returns when it isn't enrolled in an attestation
+// program — exactly the gauth use case.
+func (a *PlatformAuthenticator) Create(opts CredentialCreationOptions) (json.RawMessage, error) {
+	priv, der, err := generateP256Key()
+	if err != nil {
+		return nil, err
+	}
+
+	credID := make([]byte, 32)
+	if _, err := rand.Read(credID); err != nil {
+		return nil, fmt.Errorf("generate credential id: %w", err)
+	}
+	userID, err := unb64url(opts.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("decode user id: %w", err)
+	}
+
+	rec := credentialRecord{ID: credID, RPID: opts.RPID, UserID: userID, PrivateKey: der}
+	if err := a.save(rec); err != nil {
+		return nil, err
+	}
+
+	coseKey := encodeCOSEKey(&priv.PublicKey)
+	authData := buildAuthenticatorData(opts.RPID, 0, credID, coseKey)
+
+	attObj := cborMap([][2][]byte{
+		{cborText("fmt"), cborText("none")},
+		{cborText("attStmt"), cborMap(nil)},
+		{cborText("authData"), cborBytes(authData)},
+	})
+
+	cdj, err := clientDataJSON("webauthn.create", opts.Challenge, "https://accounts.google.com")
+	if err != nil {
+		return nil, err
+	}
+
+	result := AttestationResult{
+		ID:                b64url(credID),
+		RawID:             b64url(credID),
+		AttestationObject: b64url(attObj),
+		ClientDataJSON:    b64url(cdj),
+	}
+	return json.Marshal(result)
+}
+
+// Get implements Authenticator.Get: it signs opts.Challenge with whichever
+// stored credential matches opts.RPID (and, if given, AllowCredentials).
+func (a *PlatformAuthenticator) Get(opts CredentialRequestOptions) (json.RawMessage, error) {
+	candidates, err := a.findForRPID(opts.RPID)
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.AllowCredentials) > 0 {
+		allowed := make(map[string]bool, len(opts.AllowCredentials))
+		for _, idB64 := range opts.AllowCredentials {
+			allowed[idB64] = true
+		}
+		var filtered []credentialRecord
+		for _, c := range candidates {
+			if allowed[b64url(c.ID)] {
+				filtered = append(filtered, c)
+			}
+		}
+		candidates = filtered
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no stored credential for rpId %q", opts.RPID)
+	}
+	rec := candidates[0]
+
+	priv, err := x509.ParseECPrivateKey(rec.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("parse stored key: %w", err)
+	}
+
+	rec.SignCount++
+	if err := a.save(rec); err != nil {
+		return nil, err
+	}
+
+	authData := buildAuthenticatorData(opts.RPID, rec.SignCount, nil, nil)
+
+	cdj, err := clientDataJSON("webauthn.get", opts.Challenge, "https://accounts.google.com")
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256(append(authData, sha256Sum(cdj)...))
+	sig, err := signP256(priv, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	result := AssertionResult{
+		ID:                b64url(rec.ID),
+		RawID:             b64url(rec.ID),
+		AuthenticatorData: b64url(authData),
+		Signature:         b64url(sig),
+		UserHandle:        b64url(rec.UserID),
+		ClientDataJSON:    b64url(cdj),
+	}
+	return json.Marshal(result)
+}
+
+func sha256Sum(b []byte) []byte {
+	sum := sha256.Sum256(b)
+	return sum[:]
+}
+
+// signP256 signs digest and returns a DER-encoded ECDSA signature, the
+// format WebAuthn's AssertionResult.signature is specified to use.
+func signP256(priv *ecdsa.PrivateKey, digest []byte) ([]byte, error) {
+	r, s, err := ecdsa.Sign(rand.Reader, priv, digest)
+	if err != nil {
+		return nil, fmt.Errorf("sign: %w", err)
+	}
+	return asn1.Marshal(struct {
+		R, S *big.Int
+	}{r, s})
+}
+
+// buildAuthenticatorData assembles the authenticatorData byte string: RP ID
+// hash, flags, sign counter, and — only present on attestation (create),
+// not assertion (get) — the attested credential data block.
+func buildAuthenticatorData(rpID string, signCount uint32, credID, coseKey []byte) []byte {
+	rpHash := sha256.Sum256([]byte(rpID))
+
+	const (
+		flagUserPresent  = 0x01
+		flagUserVerified = 0x04
+		flagAttestedData = 0x40
+	)
+	flags := byte(flagUserPresent | flagUserVerified)
+	if credID != nil {
+		flags |= flagAttestedData
+	}
+
+	out := make([]byte, 0, 37)
+	out = append(out, rpHash[:]...)
+	out = append(out, flags)
+	out = append(out, byte(signCount>>24), byte(signCount>>16), byte(signCount>>8), byte(signCount))
+
+	if credID != nil {
+		out = append(out, aaguid...)
+		out = append(out, byte(len(credID)>>8), byte(len(credID)))
+		out = append(out, credID...)
+		out = append(out, coseKey...)
+	}
+	return out
+}
+
+// encodeCOSEKey encodes pub as a COSE_Key (RFC 9053 EC2 key), the format
+// WebAuthn embeds in attestedCredentialData.
+func encodeCOSEKey(pub *ecdsa.PublicKey) []byte {
+	x := pub.X.FillBytes(make([]byte, 32))
+	y := pub.Y.FillBytes(make([]byte, 32))
+	return cborMap([][2][]byte{
+		{cborInt(1), cborInt(2)},  // kty: EC2
+		{cborInt(3), cborInt(-7)}, // alg: ES256
+		{cborInt(-1), cborInt(1)}, // crv: P-256
+		{cborInt(-2), cborBytes(x)},
+		{cborInt(-3), cborBytes(y)},
+	})
+}
@@ -0,0 +1,133 @@
+package webauthn
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Minimal CBOR codec — just the major types WebAuthn's COSE keys and
+// attestation objects actually use (unsigned/negative ints, byte strings,
+// text strings, and maps). Not a general-purpose CBOR implementation, the
+// same way internal/proto hand-rolls only the protobuf wire types checkin
+// needs.
+
+func cborEncodeHead(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n <= 0xff:
+		return []byte{major<<5 | 24, byte(n)}
+	case n <= 0xffff:
+		b := make([]byte, 3)
+		b[0] = major<<5 | 25
+		binary.BigEndian.PutUint16(b[1:], uint16(n))
+		return b
+	default:
+		b := make([]byte, 5)
+		b[0] = major<<5 | 26
+		binary.BigEndian.PutUint32(b[1:], uint32(n))
+		return b
+	}
+}
+
+func cborInt(n int64) []byte {
+	if n >= 0 {
+		return cborEncodeHead(0, uint64(n))
+	}
+	return cborEncodeHead(1, uint64(-n-1))
+}
+
+func cborBytes(b []byte) []byte {
+	return append(cborEncodeHead(2, uint64(len(b))), b...)
+}
+
+func cborText(s string) []byte {
+	return append(cborEncodeHead(3, uint64(len(s))), []byte(s)...)
+}
+
+// cborMap encodes an ordered list of already-CBOR-encoded (key, value)
+// pairs — callers build keys with cborInt/cborText so a map can use either
+// (COSE keys use small ints; attestation objects use text).
+func cborMap(pairs [][2][]byte) []byte {
+	var buf bytes.Buffer
+	buf.Write(cborEncodeHead(5, uint64(len(pairs))))
+	for _, p := range pairs {
+		buf.Write(p[0])
+		buf.Write(p[1])
+	}
+	return buf.Bytes()
+}
+
+func cborDecodeHead(b []byte) (major byte, n uint64, rest []byte, err error) {
+	if len(b) == 0 {
+		return 0, 0, nil, fmt.Errorf("cbor: empty input")
+	}
+	major = b[0] >> 5
+	ai := b[0] & 0x1f
+	b = b[1:]
+	switch {
+	case ai < 24:
+		return major, uint64(ai), b, nil
+	case ai == 24:
+		if len(b) < 1 {
+			return 0, 0, nil, fmt.Errorf("cbor: truncated uint8 length")
+		}
+		return major, uint64(b[0]), b[1:], nil
+	case ai == 25:
+		if len(b) < 2 {
+			return 0, 0, nil, fmt.Errorf("cbor: truncated uint16 length")
+		}
+		return major, uint64(binary.BigEndian.Uint16(b)), b[2:], nil
+	case ai == 26:
+		if len(b) < 4 {
+			return 0, 0, nil, fmt.Errorf("cbor: truncated uint32 length")
+		}
+		return major, uint64(binary.BigEndian.Uint32(b)), b[4:], nil
+	default:
+		return 0, 0, nil, fmt.Errorf("cbor: unsupported additional info %d", ai)
+	}
+}
+
+func cborDecodeBytes(b []byte) ([]byte, []byte, error) {
+	major, n, rest, err := cborDecodeHead(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if major != 2 {
+		return nil, nil, fmt.Errorf("cbor: expected byte string, got major type %d", major)
+	}
+	if uint64(len(rest)) < n {
+		return nil, nil, fmt.Errorf("cbor: truncated byte string")
+	}
+	return rest[:n], rest[n:], nil
+}
+
+func cborDecodeText(b []byte) (string, []byte, error) {
+	major, n, rest, err := cborDecodeHead(b)
+	if err != nil {
+		return "", nil, err
+	}
+	if major != 3 {
+		return "", nil, fmt.Errorf("cbor: expected text string, got major type %d", major)
+	}
+	if uint64(len(rest)) < n {
+		return "", nil, fmt.Errorf("cbor: truncated text string")
+	}
+	return string(rest[:n]), rest[n:], nil
+}
+
+func cborDecodeInt(b []byte) (int64, []byte, error) {
+	major, n, rest, err := cborDecodeHead(b)
+	if err != nil {
+		return 0, nil, err
+	}
+	switch major {
+	case 0:
+		return int64(n), rest, nil
+	case 1:
+		return -1 - int64(n), rest, nil
+	default:
+		return 0, nil, fmt.Errorf("cbor: expected int, got major type %d", major)
+	}
+}
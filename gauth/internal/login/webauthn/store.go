@@ -0,0 +1,266 @@
+package webauthn
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// webauthnKeyringService/webauthnKeyringUser identify the credential-store
+// passphrase in the OS keyring, alongside config/secrets.go's own
+// "config-passphrase" entry under the same service name.
+const (
+	webauthnKeyringService = "gauth"
+	webauthnKeyringUser    = "webauthn-passphrase"
+)
+
+// credentialRecord is one stored passkey, CBOR-encoded at rest the same way
+// an authenticatorData/attestationObject pair would be on a real device.
+type credentialRecord struct {
+	ID         []byte `cbor:"id"`
+	RPID       string `cbor:"rpId"`
+	UserID     []byte `cbor:"userId"`
+	PrivateKey []byte `cbor:"privateKey"` // x509 EC private key, DER
+	SignCount  uint32 `cbor:"signCount"`
+}
+
+func (r credentialRecord) marshal() []byte {
+	return cborMap([][2][]byte{
+		{cborText("id"), cborBytes(r.ID)},
+		{cborText("rpId"), cborText(r.RPID)},
+		{cborText("userId"), cborBytes(r.UserID)},
+		{cborText("privateKey"), cborBytes(r.PrivateKey)},
+		{cborText("signCount"), cborInt(int64(r.SignCount))},
+	})
+}
+
+func unmarshalCredentialRecord(b []byte) (credentialRecord, error) {
+	var r credentialRecord
+	_, n, rest, err := cborDecodeHead(b)
+	if err != nil {
+		return r, fmt.Errorf("decode record map: %w", err)
+	}
+	for i := uint64(0); i < n; i++ {
+		var key string
+		key, rest, err = cborDecodeText(rest)
+		if err != nil {
+			return r, fmt.Errorf("decode record key: %w", err)
+		}
+		switch key {
+		case "id":
+			r.ID, rest, err = cborDecodeBytes(rest)
+		case "rpId":
+			r.RPID, rest, err = cborDecodeText(rest)
+		case "userId":
+			r.UserID, rest, err = cborDecodeBytes(rest)
+		case "privateKey":
+			r.PrivateKey, rest, err = cborDecodeBytes(rest)
+		case "signCount":
+			var v int64
+			v, rest, err = cborDecodeInt(rest)
+			r.SignCount = uint32(v)
+		default:
+			return r, fmt.Errorf("decode record: unknown key %q", key)
+		}
+		if err != nil {
+			return r, fmt.Errorf("decode record field %q: %w", key, err)
+		}
+	}
+	return r, nil
+}
+
+// PlatformAuthenticator is a real, local-only WebAuthn authenticator: it
+// generates and signs with genuine ECDSA P-256 keys, the same curve
+// internal/server/tls.go uses for the dev TLS cert, and stores each
+// credential encrypted at rest following session_store.go's AES-256-GCM +
+// PBKDF2 convention (one file per credential here, rather than one file
+// for the whole store, since credentials are looked up by RP ID and ID
+// independently). The passphrase the key is derived from is OS-keyring
+// backed, the same as session_store.go's, rather than a self-written
+// plaintext file.
+//
+// It is not backed by a TPM/Secure Enclave/hardware key — it's the
+// software stand-in this repo uses elsewhere for "real cryptography,
+// OS-keyring root of trust".
+type PlatformAuthenticator struct {
+	dir string
+	key []byte // derived AES-256 key
+}
+
+// DefaultStorePath returns the directory credentials are stored in,
+// mirroring config.DefaultConfigDir's use of the user config directory.
+func DefaultStorePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve user config dir: %w", err)
+	}
+	return filepath.Join(dir, "gauth", "webauthn"), nil
+}
+
+// NewPlatformAuthenticator opens (creating if necessary) the credential
+// store at dir.
+func NewPlatformAuthenticator(dir string) (*PlatformAuthenticator, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create webauthn store dir: %w", err)
+	}
+	salt, err := loadOrCreateSalt(filepath.Join(dir, "salt"))
+	if err != nil {
+		return nil, err
+	}
+	passphrase, err := loadOrCreatePassphrase()
+	if err != nil {
+		return nil, err
+	}
+	key := pbkdf2.Key([]byte(passphrase), salt, 100_000, 32, sha256.New)
+	return &PlatformAuthenticator{dir: dir, key: key}, nil
+}
+
+func loadOrCreateSalt(path string) ([]byte, error) {
+	if b, err := os.ReadFile(path); err == nil {
+		return b, nil
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("generate salt: %w", err)
+	}
+	if err := os.WriteFile(path, salt, 0o600); err != nil {
+		return nil, fmt.Errorf("write salt: %w", err)
+	}
+	return salt, nil
+}
+
+// loadOrCreatePassphrase mirrors session_store.go's handling: an explicit
+// GAUTH_WEBAUTHN_PASSPHRASE wins outright (a config-supplied override for
+// a host with no OS keyring), then a keyring entry from a prior run, then
+// a freshly generated one saved back to the keyring. If no OS keyring is
+// reachable at all and no env var is set, this fails rather than falling
+// back to a passphrase written in plaintext next to the credentials it's
+// meant to protect.
+func loadOrCreatePassphrase() (string, error) {
+	if p := os.Getenv("GAUTH_WEBAUTHN_PASSPHRASE"); p != "" {
+		return p, nil
+	}
+	if p, err := keyring.Get(webauthnKeyringService, webauthnKeyringUser); err == nil && p != "" {
+		return p, nil
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("generate passphrase: %w", err)
+	}
+	passphrase := hex.EncodeToString(raw)
+	if err := keyring.Set(webauthnKeyringService, webauthnKeyringUser, passphrase); err != nil {
+		return "", fmt.Errorf("no OS keyring available (set GAUTH_WEBAUTHN_PASSPHRASE instead): %w", err)
+	}
+	return passphrase, nil
+}
+
+func (a *PlatformAuthenticator) encrypt(plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(a.key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("create gcm: %w", err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func (a *PlatformAuthenticator) decrypt(ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(a.key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("create gcm: %w", err)
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, sealed, nil)
+}
+
+func (a *PlatformAuthenticator) credentialPath(id []byte) string {
+	return filepath.Join(a.dir, hex.EncodeToString(id)+".cred")
+}
+
+func (a *PlatformAuthenticator) save(rec credentialRecord) error {
+	ciphertext, err := a.encrypt(rec.marshal())
+	if err != nil {
+		return fmt.Errorf("encrypt credential: %w", err)
+	}
+	if err := os.WriteFile(a.credentialPath(rec.ID), ciphertext, 0o600); err != nil {
+		return fmt.Errorf("write credential: %w", err)
+	}
+	return nil
+}
+
+func (a *PlatformAuthenticator) load(id []byte) (credentialRecord, error) {
+	ciphertext, err := os.ReadFile(a.credentialPath(id))
+	if err != nil {
+		return credentialRecord{}, fmt.Errorf("read credential: %w", err)
+	}
+	plaintext, err := a.decrypt(ciphertext)
+	if err != nil {
+		return credentialRecord{}, fmt.Errorf("decrypt credential: %w", err)
+	}
+	return unmarshalCredentialRecord(plaintext)
+}
+
+// findForRPID returns every stored credential for rpID, used to answer
+// navigator.credentials.get when the page doesn't list allowCredentials.
+func (a *PlatformAuthenticator) findForRPID(rpID string) ([]credentialRecord, error) {
+	entries, err := os.ReadDir(a.dir)
+	if err != nil {
+		return nil, fmt.Errorf("read webauthn store dir: %w", err)
+	}
+	var out []credentialRecord
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".cred" {
+			continue
+		}
+		idHex := e.Name()[:len(e.Name())-len(".cred")]
+		id, err := hex.DecodeString(idHex)
+		if err != nil {
+			continue
+		}
+		rec, err := a.load(id)
+		if err != nil {
+			continue
+		}
+		if rec.RPID == rpID {
+			out = append(out, rec)
+		}
+	}
+	return out, nil
+}
+
+func generateP256Key() (*ecdsa.PrivateKey, []byte, error) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate key: %w", err)
+	}
+	der, err := x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		return nil, nil, fmt.Errorf("marshal key: %w", err)
+	}
+	return priv, der, nil
+}
@@ -0,0 +1,42 @@
+package webauthn
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Transport abstracts where a WebAuthn ceremony is actually carried out.
+// PlatformAuthenticator implements Authenticator directly (it is its own
+// transport, in effect); Transport exists for authenticator types that
+// have to talk to an external device over some wire protocol instead.
+type Transport interface {
+	// Available reports whether this transport can currently reach an
+	// authenticator (e.g. a USB HID device is plugged in).
+	Available() bool
+}
+
+// USBHIDTransport is a placeholder for real CTAP2-over-USB-HID hardware
+// key support (YubiKeys and similar). It deliberately does not pretend to
+// work: implementing it for real needs an OS-specific HID backend
+// (hidapi/libusb on Linux and macOS, a HID device handle via
+// golang.org/x/sys/windows on Windows) that this repo doesn't otherwise
+// depend on, plus the CTAP2 command/response framing on top. Wiring in a
+// fake success here would silently produce credentials no real key backs,
+// which is worse than refusing — so Create/Get just return a clear error
+// and callers fall back to PlatformAuthenticator.
+type USBHIDTransport struct{}
+
+// Available always reports false — see the type doc comment.
+func (USBHIDTransport) Available() bool { return false }
+
+// Create implements Authenticator.Create by refusing: no hardware-key
+// transport is wired up yet.
+func (USBHIDTransport) Create(opts CredentialCreationOptions) (json.RawMessage, error) {
+	return nil, fmt.Errorf("webauthn: USB HID hardware keys are not supported yet; use the platform authenticator")
+}
+
+// Get implements Authenticator.Get by refusing: no hardware-key transport
+// is wired up yet.
+func (USBHIDTransport) Get(opts CredentialRequestOptions) (json.RawMessage, error) {
+	return nil, fmt.Errorf("webauthn: USB HID hardware keys are not supported yet; use the platform authenticator")
+}
@@ -0,0 +1,78 @@
+// Package webauthn lets the WebView complete WebAuthn (FIDO2/passkey)
+// ceremonies that Google's EmbeddedSetup page initiates via
+// navigator.credentials.create/get — now the default second-factor path for
+// many accounts — instead of those calls failing silently inside the
+// embedded browser (there's no real platform authenticator under WebView2,
+// and no USB access from JS at all).
+//
+// login.BuildWebAuthnShim (in the parent package) installs the JS side that
+// intercepts navigator.credentials.* and forwards the options over the
+// webview bridge; Authenticator is the Go side that completes the ceremony
+// and returns the resulting attestation/assertion JSON.
+package webauthn
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// CredentialCreationOptions mirrors the subset of
+// PublicKeyCredentialCreationOptions the JS shim forwards over the bridge
+// for navigator.credentials.create.
+type CredentialCreationOptions struct {
+	RPID      string `json:"rpId"`
+	RPName    string `json:"rpName"`
+	UserID    string `json:"userId"` // base64url
+	UserName  string `json:"userName"`
+	Challenge string `json:"challenge"` // base64url
+}
+
+// CredentialRequestOptions mirrors the subset of
+// PublicKeyCredentialRequestOptions the JS shim forwards over the bridge for
+// navigator.credentials.get.
+type CredentialRequestOptions struct {
+	RPID             string   `json:"rpId"`
+	Challenge        string   `json:"challenge"`        // base64url
+	AllowCredentials []string `json:"allowCredentials"` // base64url credential IDs, may be empty
+}
+
+// Authenticator completes one WebAuthn ceremony and returns the
+// attestation/assertion JSON the JS shim reconstructs a real
+// PublicKeyCredential from.
+type Authenticator interface {
+	Create(opts CredentialCreationOptions) (json.RawMessage, error)
+	Get(opts CredentialRequestOptions) (json.RawMessage, error)
+}
+
+// AttestationResult is what Create returns to the page: the new credential
+// plus a "none" attestation object, matching the fields
+// PublicKeyCredential.toJSON()/parseCreationOptionsFromJSON expect.
+type AttestationResult struct {
+	ID                string `json:"id"`                // base64url credential ID
+	RawID             string `json:"rawId"`             // base64url, same as ID
+	AttestationObject string `json:"attestationObject"` // base64url CBOR
+	ClientDataJSON    string `json:"clientDataJSON"`    // base64url
+}
+
+// AssertionResult is what Get returns to the page.
+type AssertionResult struct {
+	ID                string `json:"id"`
+	RawID             string `json:"rawId"`
+	AuthenticatorData string `json:"authenticatorData"`    // base64url
+	Signature         string `json:"signature"`            // base64url
+	UserHandle        string `json:"userHandle,omitempty"` // base64url
+	ClientDataJSON    string `json:"clientDataJSON"`       // base64url
+}
+
+func b64url(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func unb64url(s string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("decode base64url: %w", err)
+	}
+	return b, nil
+}
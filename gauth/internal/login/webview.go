@@ -1,8 +1,11 @@
+//go:build windows
+
 // Package login provides WebView-based Google login.
 // Uses jchv/go-webview2 (pure Go, Windows only) for WebView2 support.
 package login
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"strings"
@@ -11,13 +14,16 @@ import (
 
 	"github.com/jchv/go-webview2"
 	"github.com/nicksrandall/gauth/internal/config"
+	"github.com/nicksrandall/gauth/internal/login/webauthn"
 )
 
-// LoginResult contains the login outcome.
-type LoginResult struct {
-	OAuthToken string
-	Cancelled  bool
-	Error      error
+// WebView2Provider is the Provider that opens an embedded WebView2 window
+// spoofing an Android device, exactly as RunWebViewLogin has always done.
+type WebView2Provider struct{}
+
+// Start implements Provider.
+func (WebView2Provider) Start(cfg *config.Config) (*LoginResult, error) {
+	return RunWebViewLogin(cfg)
 }
 
 // RunWebViewLogin opens a WebView window for Google sign-in.
@@ -54,6 +60,46 @@ func RunWebViewLogin(cfg *config.Config) (*LoginResult, error) {
 	jsBridge := BuildJSBridge(cfg)
 	w.Init(jsBridge)
 
+	// Inject the WebAuthn passthrough shim and bind it to a real
+	// platform authenticator. Passkey challenges are optional during
+	// sign-in, so a failure to open the credential store just means no
+	// passkey support this run rather than aborting the login.
+	if storeDir, err := webauthn.DefaultStorePath(); err == nil {
+		if authenticator, err := webauthn.NewPlatformAuthenticator(storeDir); err == nil {
+			w.Init(BuildWebAuthnShim())
+
+			w.Bind("__gauthWebAuthnCreate", func(reqJSON string) (string, error) {
+				var opts webauthn.CredentialCreationOptions
+				if err := json.Unmarshal([]byte(reqJSON), &opts); err != nil {
+					return "", fmt.Errorf("decode webauthn create request: %w", err)
+				}
+				log.Printf("[gauth] 🔐 WebAuthn create for rpId=%s", opts.RPID)
+				result, err := authenticator.Create(opts)
+				if err != nil {
+					return "", err
+				}
+				return string(result), nil
+			})
+
+			w.Bind("__gauthWebAuthnGet", func(reqJSON string) (string, error) {
+				var opts webauthn.CredentialRequestOptions
+				if err := json.Unmarshal([]byte(reqJSON), &opts); err != nil {
+					return "", fmt.Errorf("decode webauthn get request: %w", err)
+				}
+				log.Printf("[gauth] 🔐 WebAuthn get for rpId=%s", opts.RPID)
+				result, err := authenticator.Get(opts)
+				if err != nil {
+					return "", err
+				}
+				return string(result), nil
+			})
+		} else {
+			log.Printf("[gauth] ⚠️ WebAuthn store unavailable, passkeys disabled: %v", err)
+		}
+	} else {
+		log.Printf("[gauth] ⚠️ WebAuthn store path unavailable, passkeys disabled: %v", err)
+	}
+
 	// Bind token callback
 	w.Bind("__gauthCallback", func(action string, data string) {
 		log.Printf("[gauth] JS callback: action=%s data_len=%d", action, len(data))
@@ -0,0 +1,202 @@
+package login
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// wsConn is a minimal RFC 6455 websocket client — just enough to speak
+// Chrome DevTools Protocol (text frames carrying JSON, request/response
+// sized well under a single frame) over the ws:// URL Chrome's
+// --remote-debugging-port hands out. The repo already hand-rolls its own
+// wire protocols where a full dependency would be overkill (see
+// internal/proto for protobuf, internal/login/webauthn for CBOR); a CDP
+// client only ever needs text frames, so a full websocket library isn't
+// worth adding for it either.
+type wsConn struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+// dialWebSocket performs the HTTP Upgrade handshake against rawURL (a
+// ws://host:port/path address) and returns a connection ready for
+// writeText/readText.
+func dialWebSocket(rawURL string) (*wsConn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse websocket url: %w", err)
+	}
+	if u.Scheme != "ws" {
+		return nil, fmt.Errorf("unsupported websocket scheme %q", u.Scheme)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host += ":80"
+	}
+
+	conn, err := net.Dial("tcp", host)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", host, err)
+	}
+
+	keyBytes := make([]byte, 16)
+	if _, err := rand.Read(keyBytes); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("generate websocket key: %w", err)
+	}
+	key := base64.StdEncoding.EncodeToString(keyBytes)
+
+	path := u.Path
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	req, err := http.NewRequest(http.MethodGet, "http://"+u.Host+path, nil)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("build handshake request: %w", err)
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("write handshake: %w", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("read handshake response: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, fmt.Errorf("websocket handshake failed: %s", resp.Status)
+	}
+
+	wantAccept := wsAcceptKey(key)
+	if resp.Header.Get("Sec-WebSocket-Accept") != wantAccept {
+		conn.Close()
+		return nil, fmt.Errorf("websocket handshake: Sec-WebSocket-Accept mismatch")
+	}
+
+	return &wsConn{conn: conn, br: br}, nil
+}
+
+func wsAcceptKey(key string) string {
+	const guid = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	sum := sha1.Sum([]byte(key + guid))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// writeText sends payload as a single masked text frame, as RFC 6455
+// requires for client-to-server frames.
+func (c *wsConn) writeText(payload []byte) error {
+	mask := make([]byte, 4)
+	if _, err := rand.Read(mask); err != nil {
+		return fmt.Errorf("generate frame mask: %w", err)
+	}
+
+	var header []byte
+	const finTextOpcode = 0x81 // FIN=1, opcode=1 (text)
+	n := len(payload)
+	switch {
+	case n < 126:
+		header = []byte{finTextOpcode, 0x80 | byte(n)}
+	case n <= 0xffff:
+		header = []byte{finTextOpcode, 0x80 | 126, byte(n >> 8), byte(n)}
+	default:
+		header = []byte{finTextOpcode, 0x80 | 127, 0, 0, 0, 0, byte(n >> 24), byte(n >> 16), byte(n >> 8), byte(n)}
+	}
+	header = append(header, mask...)
+
+	masked := make([]byte, n)
+	for i, b := range payload {
+		masked[i] = b ^ mask[i%4]
+	}
+
+	if _, err := c.conn.Write(header); err != nil {
+		return fmt.Errorf("write frame header: %w", err)
+	}
+	if _, err := c.conn.Write(masked); err != nil {
+		return fmt.Errorf("write frame payload: %w", err)
+	}
+	return nil
+}
+
+// readText reads one message, reassembling continuation frames from the
+// (unmasked, since the server never masks) frames Chrome sends.
+func (c *wsConn) readText() ([]byte, error) {
+	var message []byte
+	for {
+		head := make([]byte, 2)
+		if _, err := readFull(c.br, head); err != nil {
+			return nil, fmt.Errorf("read frame header: %w", err)
+		}
+		opcode := head[0] & 0x0f
+		fin := head[0]&0x80 != 0
+		length := uint64(head[1] & 0x7f)
+
+		switch length {
+		case 126:
+			ext := make([]byte, 2)
+			if _, err := readFull(c.br, ext); err != nil {
+				return nil, fmt.Errorf("read extended length: %w", err)
+			}
+			length = uint64(ext[0])<<8 | uint64(ext[1])
+		case 127:
+			ext := make([]byte, 8)
+			if _, err := readFull(c.br, ext); err != nil {
+				return nil, fmt.Errorf("read extended length: %w", err)
+			}
+			length = 0
+			for _, b := range ext {
+				length = length<<8 | uint64(b)
+			}
+		}
+
+		payload := make([]byte, length)
+		if _, err := readFull(c.br, payload); err != nil {
+			return nil, fmt.Errorf("read frame payload: %w", err)
+		}
+
+		switch opcode {
+		case 0x1, 0x0: // text or continuation
+			message = append(message, payload...)
+		case 0x8: // close
+			return nil, fmt.Errorf("websocket closed by server")
+		default:
+			// Ping/pong and binary frames aren't used by CDP's JSON
+			// protocol; skip rather than fail the read loop.
+		}
+
+		if fin && (opcode == 0x1 || opcode == 0x0) {
+			return message, nil
+		}
+	}
+}
+
+func readFull(br *bufio.Reader, buf []byte) (int, error) {
+	n := 0
+	for n < len(buf) {
+		m, err := br.Read(buf[n:])
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+func (c *wsConn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,165 @@
+// Package mcs sits between a long-lived internal/mtalk.Client connection
+// and gauth's HTTP API: it turns Google's c2dm/register3 subscription call
+// and the raw DataMessage stream into something /api/fcm/* can hand to any
+// number of SSE listeners and webhook URLs, one set per app package.
+package mcs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nicksrandall/gauth/internal/config"
+	"github.com/nicksrandall/gauth/internal/mtalk"
+)
+
+// Message is a push payload fanned out to subscribers, the JSON shape both
+// the SSE stream and the webhook POST body use.
+type Message struct {
+	AppPackage   string            `json:"app_package"`
+	From         string            `json:"from"`
+	PersistentID string            `json:"persistent_id"`
+	Data         map[string]string `json:"data"`
+}
+
+// Manager wraps an mtalk.Client, fanning its DataMessage stream out per
+// app package to any number of Listen channels and webhook URLs — mtalk's
+// own Subscribe only holds one handler per appID, so Manager registers a
+// single dispatch function per app package the first time anyone listens to
+// it, and does the real fan-out itself.
+type Manager struct {
+	client *mtalk.Client
+
+	mu          sync.Mutex
+	subscribers map[string][]chan Message
+	webhooks    map[string][]string
+	registered  map[string]bool
+}
+
+// NewManager creates a Manager for the device check-in identified by
+// androidID/securityToken.
+func NewManager(androidID, securityToken uint64) *Manager {
+	return &Manager{
+		client:      mtalk.NewClient(androidID, securityToken),
+		subscribers: make(map[string][]chan Message),
+		webhooks:    make(map[string][]string),
+		registered:  make(map[string]bool),
+	}
+}
+
+// NewManagerFromConfig creates a Manager from cfg's checked-in identity.
+func NewManagerFromConfig(cfg *config.Config) (*Manager, error) {
+	androidID, err := strconv.ParseUint(cfg.AndroidID, 16, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parse android id: %w", err)
+	}
+	securityToken, err := strconv.ParseUint(cfg.SecurityToken, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("parse security token: %w", err)
+	}
+	return NewManager(androidID, securityToken), nil
+}
+
+// Connect opens the MCS connection and services it until ctx is cancelled,
+// the same "runs until ctx is cancelled" convention server.RefreshManager.Start
+// and mtalk.Client.Connect itself use.
+func (m *Manager) Connect(ctx context.Context) error {
+	return m.client.Connect(ctx)
+}
+
+// Listen returns a channel of Messages for appPackage and an unsubscribe
+// func to stop receiving them. The channel is buffered so one slow SSE
+// client can't block delivery to the others.
+func (m *Manager) Listen(appPackage string) (<-chan Message, func()) {
+	ch := make(chan Message, 16)
+
+	m.mu.Lock()
+	m.ensureSubscribedLocked(appPackage)
+	m.subscribers[appPackage] = append(m.subscribers[appPackage], ch)
+	m.mu.Unlock()
+
+	unsubscribe := func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		chans := m.subscribers[appPackage]
+		for i, c := range chans {
+			if c == ch {
+				m.subscribers[appPackage] = append(chans[:i], chans[i+1:]...)
+				close(ch)
+				break
+			}
+		}
+	}
+	return ch, unsubscribe
+}
+
+// AddWebhook registers url to receive an HTTP POST of every Message
+// delivered for appPackage, mirroring how push-notification gateways like
+// Gorush deliver to a caller-supplied callback.
+func (m *Manager) AddWebhook(appPackage, url string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ensureSubscribedLocked(appPackage)
+	m.webhooks[appPackage] = append(m.webhooks[appPackage], url)
+}
+
+// ensureSubscribedLocked registers a single mtalk.Client handler for
+// appPackage the first time it's needed; m.mu must already be held.
+func (m *Manager) ensureSubscribedLocked(appPackage string) {
+	if m.registered[appPackage] {
+		return
+	}
+	m.registered[appPackage] = true
+	m.client.Subscribe(appPackage, m.dispatch(appPackage))
+}
+
+func (m *Manager) dispatch(appPackage string) func(mtalk.DataMessage) {
+	return func(dm mtalk.DataMessage) {
+		msg := Message{
+			AppPackage:   appPackage,
+			From:         dm.From,
+			PersistentID: dm.PersistentID,
+			Data:         dm.Data,
+		}
+
+		m.mu.Lock()
+		chans := append([]chan Message(nil), m.subscribers[appPackage]...)
+		webhooks := append([]string(nil), m.webhooks[appPackage]...)
+		m.mu.Unlock()
+
+		for _, ch := range chans {
+			select {
+			case ch <- msg:
+			default:
+				log.Printf("[mcs] dropping message for %s: subscriber channel full", appPackage)
+			}
+		}
+		for _, url := range webhooks {
+			go postWebhook(url, msg)
+		}
+	}
+}
+
+// postWebhook delivers msg to url, logging failures rather than surfacing
+// them — a slow or unreachable webhook shouldn't block other subscribers or
+// the MCS read loop.
+func postWebhook(url string, msg Message) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("[mcs] marshal webhook payload: %v", err)
+		return
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Printf("[mcs] webhook %s: %v", url, err)
+		return
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,72 @@
+package mcs
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nicksrandall/gauth/internal/config"
+)
+
+// registerURL is Google's GCM/FCM subscription endpoint — the same one a
+// real app's Google Play Services client calls to turn an AndroidID check-in
+// plus a Firebase sender ID into a registration token the sender can push
+// to.
+const registerURL = "https://android.clients.google.com/c2dm/register3"
+
+// Register exchanges cfg's checked-in AndroidID/SecurityToken plus a
+// caller-supplied senderID for a registration token scoped to appPackage,
+// authenticated with the AidLogin scheme check-in issues rather than an
+// OAuth bearer token.
+func Register(cfg *config.Config, senderID, appPackage string) (string, error) {
+	if !cfg.HasRegistration() {
+		return "", fmt.Errorf("no device check-in; run 'gauth login' first")
+	}
+
+	form := url.Values{
+		"app":            {appPackage},
+		"sender":         {senderID},
+		"device":         {cfg.AndroidID},
+		"app_ver":        {"1"},
+		"gcm_ver":        {"224714044"},
+		"X-subtype":      {senderID},
+		"X-app_ver":      {"1"},
+		"X-osv":          {fmt.Sprintf("%d", cfg.Device.SDKVersion)},
+		"X-subscription": {senderID},
+	}
+
+	req, err := http.NewRequest("POST", registerURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", fmt.Sprintf("AidLogin %s:%s", cfg.AndroidID, cfg.SecurityToken))
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("register request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("register failed: status %d: %s", resp.StatusCode, string(body))
+	}
+
+	text := strings.TrimSpace(string(body))
+	if strings.HasPrefix(text, "Error=") {
+		return "", fmt.Errorf("register failed: %s", strings.TrimPrefix(text, "Error="))
+	}
+	const prefix = "token="
+	if !strings.HasPrefix(text, prefix) {
+		return "", fmt.Errorf("unexpected register response: %s", text)
+	}
+	return strings.TrimPrefix(text, prefix), nil
+}
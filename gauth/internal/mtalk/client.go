@@ -0,0 +1,266 @@
+// Package mtalk implements the MCS (mobile connection server) protocol
+// Google's GCM/FCM push delivery runs over — the thing a GSF registration
+// from checkin.Checkin is actually for. It opens a TLS connection to
+// mtalk.google.com:5228, authenticates with the checkin-issued
+// AndroidID/SecurityToken, and dispatches incoming DataMessageStanza
+// payloads to per-appID handlers.
+package mtalk
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nicksrandall/gauth/internal/proto"
+)
+
+// mcsHost is Google's MCS endpoint. There's no discovery mechanism — every
+// GCM/FCM client, including real Android devices, connects here directly.
+const mcsHost = "mtalk.google.com:5228"
+
+// heartbeatInterval is how often Client pings an idle connection to keep
+// it (and any NAT/firewall state in between) alive.
+const heartbeatInterval = 60 * time.Second
+
+// DataMessage is one push message delivered over MCS, already unpacked
+// from DataMessageStanza's app_data key/value pairs into a plain map.
+type DataMessage struct {
+	From         string
+	Category     string // matches the appID passed to Subscribe
+	PersistentID string
+	Data         map[string]string
+}
+
+// Client is a long-lived MCS connection, authenticated with the
+// AndroidID/SecurityToken pair checkin.Checkin returns. Connect services
+// it — handshake, heartbeats, read loop, reconnect with backoff — until
+// its context is cancelled, the same "runs until ctx is cancelled"
+// convention server.RefreshManager.Start uses.
+type Client struct {
+	androidID     uint64
+	securityToken uint64
+
+	mu       sync.Mutex
+	handlers map[string]func(DataMessage)
+}
+
+// NewClient creates a Client for the device checkin.Checkin registered.
+func NewClient(androidID, securityToken uint64) *Client {
+	return &Client{
+		androidID:     androidID,
+		securityToken: securityToken,
+		handlers:      make(map[string]func(DataMessage)),
+	}
+}
+
+// Subscribe registers handler for push messages whose category matches
+// appID (Android's package-name-keyed GCM registration). A later call for
+// the same appID replaces the handler.
+func (c *Client) Subscribe(appID string, handler func(DataMessage)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers[appID] = handler
+}
+
+func (c *Client) dispatch(msg DataMessage) {
+	c.mu.Lock()
+	handler := c.handlers[msg.Category]
+	c.mu.Unlock()
+	if handler != nil {
+		handler(msg)
+	}
+}
+
+// Connect opens the MCS connection and services it until ctx is
+// cancelled, reconnecting with exponential backoff on any error — a
+// dropped connection is routine for a push channel meant to stay open for
+// days, not a fatal condition.
+func (c *Client) Connect(ctx context.Context) error {
+	failures := 0
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		err := c.runConnection(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == nil {
+			failures = 0
+			continue // server sent a clean Close; just reconnect
+		}
+
+		failures++
+		delay := mcsBackoff(failures)
+		log.Printf("[mtalk] connection error, retrying in %s: %v", delay, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// mcsBackoff doubles from 1s up to a 5-minute cap, the same exponential
+// shape server.RefreshManager's backoff uses for account refresh retries,
+// plus jitter so a fleet of clients disconnected by the same server-side
+// event doesn't reconnect in lockstep.
+func mcsBackoff(failures int) time.Duration {
+	const base = 1 * time.Second
+	const max = 5 * time.Minute
+	d := base * time.Duration(int64(1)<<uint(min(failures, 10)))
+	if d > max {
+		d = max
+	}
+	return d + time.Duration(rand.Int63n(int64(d)/5))
+}
+
+func (c *Client) runConnection(ctx context.Context) error {
+	dialer := tls.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", mcsHost)
+	if err != nil {
+		return fmt.Errorf("dial %s: %w", mcsHost, err)
+	}
+	defer conn.Close()
+
+	stopOnCancel := make(chan struct{})
+	defer close(stopOnCancel)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stopOnCancel:
+		}
+	}()
+
+	if err := c.login(conn); err != nil {
+		return fmt.Errorf("mcs login: %w", err)
+	}
+	log.Printf("[mtalk] connected to %s", mcsHost)
+
+	return c.readLoop(conn)
+}
+
+// login performs the MCS handshake: a LoginRequest naming the AndroidID as
+// both user and resource, and the SecurityToken as auth_token — both
+// base-10 encoded, matching how real Android devices authenticate to MCS
+// post-checkin instead of using any OAuth token.
+func (c *Client) login(conn net.Conn) error {
+	userID := strconv.FormatUint(c.androidID, 10)
+	loginMsg := map[string]interface{}{
+		"1":  "gauth-1.0",
+		"2":  "mcs.android.com",
+		"3":  userID,
+		"4":  userID,
+		"5":  strconv.FormatUint(c.securityToken, 10),
+		"6":  fmt.Sprintf("android-%x", c.androidID),
+		"13": int64(2), // auth_service = ANDROID_ID
+		"14": "1",
+	}
+	payload, err := proto.Encode(loginMsg, proto.LoginRequestSchema)
+	if err != nil {
+		return fmt.Errorf("encode login request: %w", err)
+	}
+	if err := writeFrame(conn, tagLoginRequest, payload, true); err != nil {
+		return fmt.Errorf("write login request: %w", err)
+	}
+
+	f, err := readFrame(conn, true)
+	if err != nil {
+		return fmt.Errorf("read login response: %w", err)
+	}
+	if f.tag != tagLoginResponse {
+		return fmt.Errorf("expected login response (tag %d), got tag %d", tagLoginResponse, f.tag)
+	}
+
+	resp, err := proto.Decode(f.payload, proto.LoginResponseSchema)
+	if err != nil {
+		return fmt.Errorf("decode login response: %w", err)
+	}
+	log.Printf("[mtalk] login response: %v", resp)
+	return nil
+}
+
+// readLoop services one connection until it closes or errors, dispatching
+// DataMessageStanza frames and acking HeartbeatPings, alongside a
+// goroutine that sends our own pings on heartbeatInterval.
+func (c *Client) readLoop(conn net.Conn) error {
+	stopHeartbeat := make(chan struct{})
+	defer close(stopHeartbeat)
+	go c.heartbeatLoop(conn, stopHeartbeat)
+
+	first := true
+	for {
+		f, err := readFrame(conn, first)
+		first = false
+		if err != nil {
+			return fmt.Errorf("read frame: %w", err)
+		}
+
+		switch f.tag {
+		case tagDataMessageStanza:
+			c.handleDataMessage(f.payload)
+		case tagHeartbeatPing:
+			if err := writeFrame(conn, tagHeartbeatAck, nil, false); err != nil {
+				return fmt.Errorf("ack heartbeat: %w", err)
+			}
+		case tagHeartbeatAck:
+			// nothing to do — receiving one just confirms the connection
+			// is alive.
+		case tagClose:
+			return nil
+		default:
+			log.Printf("[mtalk] ignoring frame tag %d (%d bytes)", f.tag, len(f.payload))
+		}
+	}
+}
+
+func (c *Client) handleDataMessage(payload []byte) {
+	decoded, err := proto.Decode(payload, proto.DataMessageStanzaSchema)
+	if err != nil {
+		log.Printf("[mtalk] decode data message: %v", err)
+		return
+	}
+
+	msg := DataMessage{Data: make(map[string]string)}
+	msg.From, _ = decoded["3"].(string)
+	msg.Category, _ = decoded["5"].(string)
+	msg.PersistentID, _ = decoded["8"].(string)
+
+	if items, ok := decoded["6"].([]interface{}); ok {
+		for _, item := range items {
+			kv, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			key, _ := kv["1"].(string)
+			value, _ := kv["2"].(string)
+			msg.Data[key] = value
+		}
+	}
+
+	c.dispatch(msg)
+}
+
+func (c *Client) heartbeatLoop(conn net.Conn, stop <-chan struct{}) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := writeFrame(conn, tagHeartbeatPing, nil, false); err != nil {
+				log.Printf("[mtalk] send heartbeat: %v", err)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package mtalk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// mcsVersion is the MCS protocol version gauth speaks; the first frame in
+// each direction is prefixed with this byte, and the server will close the
+// connection if it doesn't match.
+const mcsVersion = 41
+
+// MCS frame tags — which protobuf message type (tag byte) + varint length
+// + payload follows. Only the tags this client actually handles are
+// named; unrecognized tags are logged and skipped (see readLoop).
+const (
+	tagHeartbeatPing     = 0
+	tagHeartbeatAck      = 1
+	tagLoginRequest      = 2
+	tagLoginResponse     = 3
+	tagClose             = 4
+	tagDataMessageStanza = 8
+)
+
+// frame is one MCS message: a tag identifying which protobuf schema to
+// decode payload with, per the tag constants above.
+type frame struct {
+	tag     byte
+	payload []byte
+}
+
+// writeFrame writes one frame. includeVersion must be true only for the
+// very first frame a client sends (the LoginRequest) — every frame after
+// that, in both directions, omits the version byte.
+func writeFrame(w io.Writer, tag byte, payload []byte, includeVersion bool) error {
+	var buf bytes.Buffer
+	if includeVersion {
+		buf.WriteByte(mcsVersion)
+	}
+	buf.WriteByte(tag)
+
+	var lenBuf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(lenBuf[:], uint64(len(payload)))
+	buf.Write(lenBuf[:n])
+	buf.Write(payload)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// readFrame reads one frame. first must be true only for the very first
+// frame read off a freshly dialed connection (the server's LoginResponse),
+// since that's the one time the server also sends the version byte.
+func readFrame(r io.Reader, first bool) (frame, error) {
+	if first {
+		var verBuf [1]byte
+		if _, err := io.ReadFull(r, verBuf[:]); err != nil {
+			return frame{}, fmt.Errorf("read version byte: %w", err)
+		}
+		if verBuf[0] != mcsVersion {
+			return frame{}, fmt.Errorf("unexpected MCS version %d (want %d)", verBuf[0], mcsVersion)
+		}
+	}
+
+	var tagBuf [1]byte
+	if _, err := io.ReadFull(r, tagBuf[:]); err != nil {
+		return frame{}, fmt.Errorf("read tag byte: %w", err)
+	}
+
+	length, err := readUvarint(r)
+	if err != nil {
+		return frame{}, fmt.Errorf("read length: %w", err)
+	}
+
+	payload := make([]byte, length)
+	if length > 0 {
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return frame{}, fmt.Errorf("read payload: %w", err)
+		}
+	}
+
+	return frame{tag: tagBuf[0], payload: payload}, nil
+}
+
+// readUvarint reads a protobuf-style varint one byte at a time, since MCS
+// frames aren't buffered ahead of time the way binary.Uvarint expects.
+func readUvarint(r io.Reader) (uint64, error) {
+	var x uint64
+	var s uint
+	var b [1]byte
+	for {
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return 0, err
+		}
+		if b[0] < 0x80 {
+			if s >= 63 && b[0] > 1 {
+				return 0, fmt.Errorf("varint overflows uint64")
+			}
+			return x | uint64(b[0])<<s, nil
+		}
+		x |= uint64(b[0]&0x7f) << s
+		s += 7
+	}
+}
@@ -83,6 +83,164 @@ func DecodeMessage(data []byte) (map[string]interface{}, error) {
 	return result, nil
 }
 
+// Decode decodes protobuf bytes into a map[string]interface{} using schema
+// to resolve each field's Go representation, the decode-side counterpart
+// to Encode. Unlike DecodeMessage it doesn't have to guess whether a
+// length-delimited field is a string, raw bytes, or a nested message —
+// schema says so directly — and it collects Repeated fields into
+// []interface{} (including packed-repeated-varint fields, which the wire
+// format stores as a single bytes field containing a stream of varints
+// rather than one tag per value) instead of only upgrading to a slice once
+// a second occurrence shows up.
+//
+// Fields with no entry in schema fall back to DecodeMessage's
+// auto-detection, so schemas only need to cover the fields callers care
+// about.
+func Decode(data []byte, schema MessageType) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	pos := 0
+
+	for pos < len(data) {
+		tag, n := binary.Uvarint(data[pos:])
+		if n <= 0 {
+			return nil, fmt.Errorf("failed to read tag at pos %d", pos)
+		}
+		pos += n
+
+		fieldNum := int(tag >> 3)
+		wireType := WireType(tag & 0x7)
+		key := fmt.Sprintf("%d", fieldNum)
+		fieldDef, hasSchema := schema[key]
+
+		switch wireType {
+		case WireVarint:
+			val, n := binary.Uvarint(data[pos:])
+			if n <= 0 {
+				return nil, fmt.Errorf("failed to read varint for field %d", fieldNum)
+			}
+			pos += n
+			addDecoded(result, key, fieldDef, decodeVarintValue(val, fieldDef))
+
+		case WireFixed64:
+			if pos+8 > len(data) {
+				return nil, fmt.Errorf("not enough data for fixed64 field %d", fieldNum)
+			}
+			val := binary.LittleEndian.Uint64(data[pos : pos+8])
+			pos += 8
+			addDecoded(result, key, fieldDef, val)
+
+		case WireFixed32:
+			if pos+4 > len(data) {
+				return nil, fmt.Errorf("not enough data for fixed32 field %d", fieldNum)
+			}
+			val := binary.LittleEndian.Uint32(data[pos : pos+4])
+			pos += 4
+			addDecoded(result, key, fieldDef, math.Float32frombits(val))
+
+		case WireBytes:
+			length, n := binary.Uvarint(data[pos:])
+			if n <= 0 {
+				return nil, fmt.Errorf("failed to read length for field %d", fieldNum)
+			}
+			pos += n
+			if pos+int(length) > len(data) {
+				return nil, fmt.Errorf("not enough data for bytes field %d", fieldNum)
+			}
+			payload := data[pos : pos+int(length)]
+			pos += int(length)
+
+			if hasSchema && fieldDef.Repeated && fieldDef.Type == TypeInt {
+				// Packed repeated varint: one bytes field holding a
+				// back-to-back stream of varints instead of one tag per
+				// value.
+				values, err := decodePackedVarints(payload)
+				if err != nil {
+					return nil, fmt.Errorf("field %d: %w", fieldNum, err)
+				}
+				for _, v := range values {
+					addDecoded(result, key, fieldDef, v)
+				}
+				continue
+			}
+
+			value, err := decodeBytesValue(payload, fieldDef, hasSchema)
+			if err != nil {
+				return nil, fmt.Errorf("field %d: %w", fieldNum, err)
+			}
+			addDecoded(result, key, fieldDef, value)
+
+		default:
+			return nil, fmt.Errorf("unknown wire type %d for field %d", wireType, fieldNum)
+		}
+	}
+
+	return result, nil
+}
+
+// decodeVarintValue renders a raw varint according to fieldDef.Type; with
+// no schema it falls back to DecodeMessage's plain int64.
+func decodeVarintValue(val uint64, fieldDef FieldDef) interface{} {
+	if fieldDef.Type == TypeBool {
+		return val != 0
+	}
+	return int64(val)
+}
+
+// decodeBytesValue renders a length-delimited field according to
+// fieldDef.Type; with no schema it falls back to DecodeMessage's
+// nested-message/string/bytes guessing.
+func decodeBytesValue(payload []byte, fieldDef FieldDef, hasSchema bool) (interface{}, error) {
+	if !hasSchema {
+		if nested, err := DecodeMessage(payload); err == nil && len(nested) > 0 {
+			return nested, nil
+		}
+		if isLikelyString(payload) {
+			return string(payload), nil
+		}
+		return payload, nil
+	}
+
+	switch fieldDef.Type {
+	case TypeMessage:
+		nestedSchema := MessageType(fieldDef.MessageDef)
+		return Decode(payload, nestedSchema)
+	case TypeString:
+		return string(payload), nil
+	case TypeBytes:
+		return payload, nil
+	default:
+		return nil, fmt.Errorf("schema says %s but wire type is length-delimited", fieldDef.Type)
+	}
+}
+
+// decodePackedVarints splits a packed-repeated-varint field's payload into
+// its individual values.
+func decodePackedVarints(payload []byte) ([]int64, error) {
+	var values []int64
+	pos := 0
+	for pos < len(payload) {
+		val, n := binary.Uvarint(payload[pos:])
+		if n <= 0 {
+			return nil, fmt.Errorf("failed to read packed varint at offset %d", pos)
+		}
+		pos += n
+		values = append(values, int64(val))
+	}
+	return values, nil
+}
+
+// addDecoded appends value under key, always as a slice once fieldDef
+// declares the field Repeated so callers don't need to guess whether a
+// single occurrence means "one value" or "a slice of one".
+func addDecoded(result map[string]interface{}, key string, fieldDef FieldDef, value interface{}) {
+	if fieldDef.Repeated {
+		existing, _ := result[key].([]interface{})
+		result[key] = append(existing, value)
+		return
+	}
+	addToResult(result, key, value)
+}
+
 func addToResult(result map[string]interface{}, key string, value interface{}) {
 	if existing, ok := result[key]; ok {
 		// Convert to repeated field
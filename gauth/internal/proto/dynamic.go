@@ -0,0 +1,177 @@
+package proto
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// GAUTH_PROTO_DESCRIPTORS points DecodeMessageAs at a compiled schema: either
+// a .proto source file (compiled on the fly with protocompile) or a binary
+// FileDescriptorSet (as produced by `protoc -o out.bin --descriptor_set_out`).
+const protoDescriptorsEnv = "GAUTH_PROTO_DESCRIPTORS"
+
+// LoadDescriptors compiles or parses the schema at path into a
+// protoregistry.Files, auto-detecting a .proto source file (by extension)
+// versus a serialized FileDescriptorSet.
+func LoadDescriptors(path string) (*protoregistry.Files, error) {
+	if filepath.Ext(path) == ".proto" {
+		return compileProtoFile(path)
+	}
+	return loadDescriptorSet(path)
+}
+
+// compileProtoFile compiles a single .proto file (with its own directory as
+// the import path, so sibling imports resolve) into a protoregistry.Files.
+func compileProtoFile(path string) (*protoregistry.Files, error) {
+	dir := filepath.Dir(path)
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			ImportPaths: []string{dir},
+		}),
+	}
+	compiled, err := compiler.Compile(context.Background(), filepath.Base(path))
+	if err != nil {
+		return nil, fmt.Errorf("compile %s: %w", path, err)
+	}
+
+	files := &protoregistry.Files{}
+	for _, f := range compiled {
+		if err := files.RegisterFile(f); err != nil {
+			return nil, fmt.Errorf("register %s: %w", f.Path(), err)
+		}
+	}
+	return files, nil
+}
+
+// loadDescriptorSet reads a binary FileDescriptorSet (google.protobuf's own
+// wire format) from disk and builds its descriptors.
+func loadDescriptorSet(path string) (*protoregistry.Files, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read descriptor set %s: %w", path, err)
+	}
+	var fdSet descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(data, &fdSet); err != nil {
+		return nil, fmt.Errorf("parse descriptor set %s: %w", path, err)
+	}
+	files, err := protodesc.NewFiles(&fdSet)
+	if err != nil {
+		return nil, fmt.Errorf("build descriptors from %s: %w", path, err)
+	}
+	return files, nil
+}
+
+// descriptorsFromEnv loads the schema pointed to by GAUTH_PROTO_DESCRIPTORS,
+// or returns nil if it's unset.
+func descriptorsFromEnv() (*protoregistry.Files, error) {
+	path := os.Getenv(protoDescriptorsEnv)
+	if path == "" {
+		return nil, nil
+	}
+	return LoadDescriptors(path)
+}
+
+// DecodeMessageAs decodes data as msgName (a fully-qualified protobuf
+// message name, e.g. "checkin_proto.AndroidCheckinResponse") using the
+// schema loaded from GAUTH_PROTO_DESCRIPTORS, producing a map keyed by the
+// message's real field names rather than field numbers. Unlike DecodeMessage
+// and Decode, it walks a google.golang.org/protobuf dynamicpb message built
+// from the real FileDescriptor rather than re-implementing wire-format
+// decoding by hand, so packed repeated fields, sint32/64 zig-zag, map<K,V>
+// entries, and groups all fall out of the standard library's own unmarshal
+// instead of needing bespoke handling here.
+//
+// If GAUTH_PROTO_DESCRIPTORS isn't set, or msgName isn't found in it,
+// DecodeMessageAs falls back to the heuristic DecodeMessage.
+func DecodeMessageAs(data []byte, msgName string) (map[string]interface{}, error) {
+	files, err := descriptorsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if files == nil {
+		return DecodeMessage(data)
+	}
+
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(msgName))
+	if err != nil {
+		return DecodeMessage(data)
+	}
+	md, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a message type", msgName)
+	}
+
+	msg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(data, msg); err != nil {
+		return nil, fmt.Errorf("unmarshal as %s: %w", msgName, err)
+	}
+	return messageToMap(msg), nil
+}
+
+// messageToMap walks msg's populated fields into a map[string]interface{}
+// keyed by field name, recursing into nested messages/maps/lists via
+// protoreflect so every field kind (including ones DecodeMessage can't tell
+// apart from the wire bytes alone, like sint32 vs plain int32) renders with
+// its real Go value.
+func messageToMap(msg protoreflect.Message) map[string]interface{} {
+	result := make(map[string]interface{})
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		result[string(fd.Name())] = fieldValue(fd, v)
+		return true
+	})
+	return result
+}
+
+// fieldValue renders a single populated field's value according to its
+// descriptor: maps become map[string]interface{}, repeated fields become
+// []interface{}, and message/enum/scalar values render via scalarValue.
+func fieldValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch {
+	case fd.IsMap():
+		out := make(map[string]interface{})
+		v.Map().Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+			out[k.String()] = scalarValue(fd.MapValue(), mv)
+			return true
+		})
+		return out
+
+	case fd.IsList():
+		list := v.List()
+		out := make([]interface{}, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			out[i] = scalarValue(fd, list.Get(i))
+		}
+		return out
+
+	default:
+		return scalarValue(fd, v)
+	}
+}
+
+// scalarValue renders a single (non-map, non-repeated) value according to
+// fd's kind.
+func scalarValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return messageToMap(v.Message())
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return int32(v.Enum())
+	case protoreflect.BytesKind:
+		return append([]byte(nil), v.Bytes()...)
+	default:
+		return v.Interface()
+	}
+}
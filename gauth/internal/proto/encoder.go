@@ -1,5 +1,8 @@
-// Package proto provides protobuf encoding/decoding without external dependencies.
-// Adapted from gphotos-go's schema-driven encoder.
+// Package proto provides protobuf encoding/decoding. The core codec
+// (Encode/Decode/DecodeMessage) is dependency-free and adapted from
+// gphotos-go's schema-driven encoder; DecodeMessageAs (dynamic.go) is the
+// one schema-aware exception, using google.golang.org/protobuf's reflection
+// API against a user-supplied .proto file or FileDescriptorSet.
 package proto
 
 import (
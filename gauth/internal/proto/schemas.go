@@ -66,3 +66,79 @@ var CheckinRequestSchema = MessageType{
 	"21": {Type: TypeString},                 // userName
 	"22": {Type: TypeInt},                    // userSerialNumber
 }
+
+// CheckinResponseSchema defines the protobuf schema for
+// AndroidCheckinResponse, just the fields checkin.Checkin reads out of it.
+var CheckinResponseSchema = MessageType{
+	"1":  {Type: TypeBool},                   // statsOk
+	"3":  {Type: TypeString},                 // digest
+	"6":  {Type: TypeBool},                   // intent
+	"7":  {Type: TypeInt},                    // androidId (fixed64 on the wire)
+	"8":  {Type: TypeInt},                    // securityToken (fixed64 on the wire)
+	"9":  {Type: TypeBool},                   // settingsDiff
+	"10": {Type: TypeString, Repeated: true}, // deleteSetting
+}
+
+// MCS (mobile connection server) protobuf schemas translated from
+// Google's mcs.proto, used by internal/mtalk for the GCM/FCM push
+// connection checkin.Checkin's AndroidID + SecurityToken are good for.
+
+// LoginRequestSchema is the handshake message sent once per MCS
+// connection, authenticating with the checkin-issued AndroidID/
+// SecurityToken instead of a username/password.
+var LoginRequestSchema = MessageType{
+	"1": {Type: TypeString}, // id (client version string)
+	"2": {Type: TypeString}, // domain ("mcs.android.com")
+	"3": {Type: TypeString}, // user (AndroidID, base 10)
+	"4": {Type: TypeString}, // resource (same as user)
+	"5": {Type: TypeString}, // authToken (SecurityToken, base 10)
+	"6": {Type: TypeString}, // deviceId ("android-" + AndroidID in hex)
+	"7": {Type: TypeMessage, Repeated: true, MessageDef: map[string]FieldDef{ // setting
+		"1": {Type: TypeString}, // name
+		"2": {Type: TypeString}, // value
+	}},
+	"8":  {Type: TypeString, Repeated: true}, // receivedPersistentId
+	"12": {Type: TypeInt},                    // accountId
+	"13": {Type: TypeInt},                    // authService (2 = ANDROID_ID)
+	"14": {Type: TypeString},                 // networkType
+}
+
+// LoginResponseSchema is MCS's reply to LoginRequest.
+var LoginResponseSchema = MessageType{
+	"1": {Type: TypeString}, // id
+	"2": {Type: TypeString}, // jwt
+	"5": {Type: TypeInt},    // serverTimestamp
+}
+
+// appDataSchema is the (key, value) pair DataMessageStanza carries its
+// actual push payload in, repeated once per key.
+var appDataSchema = map[string]FieldDef{
+	"1": {Type: TypeString}, // key
+	"2": {Type: TypeString}, // value
+}
+
+// DataMessageStanzaSchema is an actual push message delivered over MCS —
+// what internal/mtalk.Client dispatches to Subscribe handlers.
+var DataMessageStanzaSchema = MessageType{
+	"2":  {Type: TypeString},                                             // id
+	"3":  {Type: TypeString},                                             // from
+	"4":  {Type: TypeString},                                             // to
+	"5":  {Type: TypeString},                                             // category (the subscribed appID)
+	"6":  {Type: TypeMessage, Repeated: true, MessageDef: appDataSchema}, // appData
+	"8":  {Type: TypeString},                                             // persistentId
+	"9":  {Type: TypeInt},                                                // streamId
+	"10": {Type: TypeInt},                                                // lastStreamIdReceived
+	"13": {Type: TypeString},                                             // registrationId
+	"16": {Type: TypeInt},                                                // sent
+	"17": {Type: TypeInt},                                                // ttl
+}
+
+// HeartbeatPingSchema/HeartbeatAckSchema are MCS's keepalive messages;
+// internal/mtalk only needs to round-trip the stream ack counters.
+var HeartbeatPingSchema = MessageType{
+	"1": {Type: TypeString}, // streamId
+	"2": {Type: TypeInt},    // lastStreamIdReceived
+	"3": {Type: TypeInt},    // status
+}
+
+var HeartbeatAckSchema = HeartbeatPingSchema
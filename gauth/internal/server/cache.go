@@ -0,0 +1,34 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/nicksrandall/gauth/internal/auth"
+	"github.com/nicksrandall/gauth/internal/config"
+)
+
+// registerCacheRoutes adds GET/DELETE /api/cache for inspecting and
+// invalidating the in-memory token cache (internal/tokencache, fronting
+// auth.GetServiceToken), and GET /metrics exposing its counters in
+// Prometheus text format.
+func registerCacheRoutes(mux *http.ServeMux, cfg *config.Config) {
+	mux.HandleFunc("/api/cache", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, auth.CacheStats())
+		case http.MethodDelete:
+			if err := auth.ClearTokenCache(r.URL.Query().Get("scope")); err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, auth.CacheStats())
+		default:
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "use GET or DELETE"})
+		}
+	})
+
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		auth.WriteCacheMetrics(w)
+	})
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/nicksrandall/gauth/internal/config"
+	"github.com/nicksrandall/gauth/internal/mcs"
+)
+
+// FCMRegisterRequest is the JSON body for POST /api/fcm/register.
+type FCMRegisterRequest struct {
+	SenderID   string `json:"sender_id"`
+	AppPackage string `json:"app_package"`
+}
+
+// FCMRegisterResponse is the JSON response for POST /api/fcm/register.
+type FCMRegisterResponse struct {
+	RegistrationToken string `json:"registration_token,omitempty"`
+	Error             string `json:"error,omitempty"`
+}
+
+// fcmManager lazily creates and connects one mcs.Manager per account, the
+// first time one is needed for that account — cfg may not have a device
+// check-in yet when Start runs. Once built it re-subscribes every app
+// package with a persisted registration, so a restart resumes delivery
+// without the caller having to call /api/fcm/register again. Keying by
+// cfg.Email (rather than holding a single shared Manager) matters because
+// every /api/fcm/* route is reachable for any signed-in account via
+// accountConfigForRequest, not just the one `gauth serve` started with.
+type fcmManager struct {
+	mu   sync.Mutex
+	mgrs map[string]*mcs.Manager
+	errs map[string]error
+}
+
+func (f *fcmManager) get(cfg *config.Config) (*mcs.Manager, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.mgrs == nil {
+		f.mgrs = map[string]*mcs.Manager{}
+		f.errs = map[string]error{}
+	}
+
+	if mgr, ok := f.mgrs[cfg.Email]; ok {
+		return mgr, nil
+	}
+	if err, ok := f.errs[cfg.Email]; ok {
+		return nil, err
+	}
+
+	if !cfg.HasRegistration() {
+		err := fmt.Errorf("no device check-in; run 'gauth login' first")
+		f.errs[cfg.Email] = err
+		return nil, err
+	}
+	mgr, err := mcs.NewManagerFromConfig(cfg)
+	if err != nil {
+		f.errs[cfg.Email] = err
+		return nil, err
+	}
+	f.mgrs[cfg.Email] = mgr
+
+	for appPackage := range cfg.FCMRegistrations {
+		_, unsubscribe := mgr.Listen(appPackage)
+		unsubscribe() // just registers the mtalk subscription; nobody's listening yet
+	}
+
+	email := cfg.Email
+	go func() {
+		if err := mgr.Connect(context.Background()); err != nil {
+			log.Printf("[mcs] connection ended for %s: %v", email, err)
+		}
+	}()
+	return mgr, nil
+}
+
+// registerFCMRoutes adds the FCM/GCM push endpoints backed by internal/mcs:
+// register a sender+app for a registration token, stream decrypted pushes
+// over SSE, or have them POSTed to a webhook URL instead.
+func registerFCMRoutes(mux *http.ServeMux, cfg *config.Config) {
+	fm := &fcmManager{}
+
+	mux.HandleFunc("/api/fcm/register", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, FCMRegisterResponse{Error: "use POST"})
+			return
+		}
+
+		var req FCMRegisterRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, FCMRegisterResponse{Error: "invalid JSON: " + err.Error()})
+			return
+		}
+		if req.SenderID == "" || req.AppPackage == "" {
+			writeJSON(w, http.StatusBadRequest, FCMRegisterResponse{Error: "sender_id and app_package are required"})
+			return
+		}
+
+		reqCfg := accountConfigForRequest(cfg, r)
+		token, err := mcs.Register(reqCfg, req.SenderID, req.AppPackage)
+		if err != nil {
+			writeJSON(w, http.StatusBadGateway, FCMRegisterResponse{Error: err.Error()})
+			return
+		}
+
+		if reqCfg.FCMRegistrations == nil {
+			reqCfg.FCMRegistrations = map[string]string{}
+		}
+		reqCfg.FCMRegistrations[req.AppPackage] = token
+		if err := reqCfg.SaveAccount(); err != nil {
+			log.Printf("[mcs] save registration: %v", err)
+		}
+
+		writeJSON(w, http.StatusOK, FCMRegisterResponse{RegistrationToken: token})
+	})
+
+	mux.HandleFunc("/api/fcm/stream", func(w http.ResponseWriter, r *http.Request) {
+		appPackage := r.URL.Query().Get("app_package")
+		if appPackage == "" {
+			http.Error(w, "app_package query parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		reqCfg := accountConfigForRequest(cfg, r)
+		mgr, err := fm.get(reqCfg)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming not supported", http.StatusInternalServerError)
+			return
+		}
+
+		messages, unsubscribe := mgr.Listen(appPackage)
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		for {
+			select {
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				data, err := json.Marshal(msg)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "data: %s\n\n", data)
+				flusher.Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+
+	mux.HandleFunc("/api/fcm/webhook", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "use POST"})
+			return
+		}
+
+		var req struct {
+			AppPackage string `json:"app_package"`
+			URL        string `json:"url"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON: " + err.Error()})
+			return
+		}
+		if req.AppPackage == "" || req.URL == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "app_package and url are required"})
+			return
+		}
+
+		reqCfg := accountConfigForRequest(cfg, r)
+		mgr, err := fm.get(reqCfg)
+		if err != nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": err.Error()})
+			return
+		}
+		mgr.AddWebhook(req.AppPackage, req.URL)
+		writeJSON(w, http.StatusOK, map[string]string{"status": "subscribed"})
+	})
+}
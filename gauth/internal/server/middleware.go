@@ -0,0 +1,297 @@
+package server
+
+import (
+	"container/list"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"math/bits"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/nicksrandall/gauth/internal/config"
+)
+
+// hashcashChallengeTTL is how long a client has to solve and submit a
+// proof-of-work challenge before it's treated as never having been issued.
+const hashcashChallengeTTL = 5 * time.Minute
+
+// hashcashAcceptedCapacity bounds the recently-accepted-proofs LRU that
+// guards against replaying the same solved challenge twice.
+const hashcashAcceptedCapacity = 4096
+
+// authMiddleware wraps next with whichever API access-control mode authCfg
+// configures: a bearer API key (checked against cfg.APISecret and
+// authCfg.APIKeys with a constant-time compare) guards every request, or —
+// if no key is configured — a hashcash proof-of-work challenge guards
+// every request, since anyone who can reach any route here can eventually
+// reach /api/token, /oauth2/token, or the MITM CONNECT tunnel and mint a
+// usable Google token. mTLS isn't checked here: it's enforced earlier,
+// during the TLS handshake itself, by the ClientCAs/ClientAuth
+// LoadServerTLSConfig sets when authCfg.ClientCA is configured. A
+// per-identity rate limiter (keyed by the caller's API key, or its IP if
+// none was required) applies on top of whichever mode let the request
+// through.
+func authMiddleware(cfg *config.Config, authCfg config.APIAuthConfig, next http.Handler) http.Handler {
+	hc := newHashcashServer(authCfg.HashcashDifficulty)
+	limiters := newLimiterRegistry(authCfg.RateLimitPerSecond, authCfg.RateLimitBurst)
+	requireKey := len(authCfg.APIKeys) > 0 || cfg.APISecret != ""
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := bearerKey(r)
+		validKey := ok && apiKeyAllowed(cfg, authCfg, key)
+
+		switch {
+		case requireKey && !validKey:
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid or missing bearer token"})
+			return
+		case !requireKey && authCfg.HashcashEnabled:
+			if !hc.enforce(w, r) {
+				return
+			}
+		case requireKey && validKey:
+			// falls through to the rate limiter below
+		default:
+			// Neither an API key nor hashcash is configured. This is only
+			// safe because server.go's bindHost keeps the listener on
+			// 127.0.0.1 whenever authCfg.Configured() is false — there's
+			// no remote attacker to deny here. If that invariant ever
+			// changes, this case needs to start denying too.
+		}
+
+		identity := key
+		if identity == "" {
+			identity = clientIP(r)
+		}
+		if !limiters.allow(identity) {
+			writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": "rate limit exceeded"})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// bearerKey extracts the token from an "Authorization: Bearer <token>"
+// header, if present.
+func bearerKey(r *http.Request) (string, bool) {
+	const prefix = "Bearer "
+	h := r.Header.Get("Authorization")
+	if len(h) <= len(prefix) || h[:len(prefix)] != prefix {
+		return "", false
+	}
+	return h[len(prefix):], true
+}
+
+// apiKeyAllowed reports whether key matches cfg.APISecret (the older,
+// single-key field) or any entry in authCfg.APIKeys, comparing each
+// candidate in constant time so a timing side-channel can't help an
+// attacker guess a valid key one byte at a time.
+func apiKeyAllowed(cfg *config.Config, authCfg config.APIAuthConfig, key string) bool {
+	if cfg.APISecret != "" && subtle.ConstantTimeCompare([]byte(key), []byte(cfg.APISecret)) == 1 {
+		return true
+	}
+	for _, candidate := range authCfg.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(candidate)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the request's remote IP without its port, falling back
+// to the full RemoteAddr if it can't be split (e.g. in tests against a
+// listener without a real address).
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// --- rate limiting ---
+
+// limiterRegistry hands out a golang.org/x/time/rate.Limiter per identity
+// (API key or IP), creating one lazily on first use. A nil/zero-valued
+// registry (RateLimitPerSecond <= 0) allows everything, so rate limiting
+// stays off unless explicitly configured.
+type limiterRegistry struct {
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+	rps      rate.Limit
+	burst    int
+}
+
+func newLimiterRegistry(perSecond float64, burst int) *limiterRegistry {
+	if perSecond <= 0 {
+		return &limiterRegistry{}
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+	return &limiterRegistry{limiters: map[string]*rate.Limiter{}, rps: rate.Limit(perSecond), burst: burst}
+}
+
+func (lr *limiterRegistry) allow(identity string) bool {
+	if lr.limiters == nil {
+		return true
+	}
+	lr.mu.Lock()
+	l, ok := lr.limiters[identity]
+	if !ok {
+		l = rate.NewLimiter(lr.rps, lr.burst)
+		lr.limiters[identity] = l
+	}
+	lr.mu.Unlock()
+	return l.Allow()
+}
+
+// --- hashcash proof-of-work for /api/token ---
+
+// hashcashChallengeResponse is the 402 body a client gets back the first
+// time it hits /api/token with hashcash enabled and no valid proof yet.
+type hashcashChallengeResponse struct {
+	Challenge  string `json:"challenge"`  // hex-encoded random bytes
+	Difficulty int    `json:"difficulty"` // required leading zero bits of sha256(challenge||nonce)
+}
+
+type pendingChallenge struct {
+	challenge []byte
+	issuedAt  time.Time
+}
+
+// hashcashServer issues and verifies per-client proof-of-work challenges.
+// Challenges are keyed by client IP rather than returned to and replayed by
+// the client, since the request only carries the solved nonce back — the
+// server has to remember which challenge it handed out to match it against.
+type hashcashServer struct {
+	difficulty int
+
+	mu      sync.Mutex
+	pending map[string]pendingChallenge
+
+	acceptedMu sync.Mutex
+	accepted   map[string]*list.Element
+	acceptedLL *list.List
+}
+
+func newHashcashServer(difficulty int) *hashcashServer {
+	if difficulty <= 0 {
+		difficulty = 20
+	}
+	return &hashcashServer{
+		difficulty: difficulty,
+		pending:    map[string]pendingChallenge{},
+		accepted:   map[string]*list.Element{},
+		acceptedLL: list.New(),
+	}
+}
+
+// enforce implements the /api/token hashcash dance: no X-Hashcash header
+// yet issues a fresh challenge (402), an invalid or expired one is
+// rejected (402), and a valid one lets the request through.
+func (h *hashcashServer) enforce(w http.ResponseWriter, r *http.Request) bool {
+	ip := clientIP(r)
+	nonceHex := r.Header.Get("X-Hashcash")
+	if nonceHex == "" {
+		h.issueChallenge(w, ip)
+		return false
+	}
+	if !h.verify(ip, nonceHex) {
+		writeJSON(w, http.StatusPaymentRequired, map[string]string{"error": "invalid, expired, or already-used hashcash proof"})
+		return false
+	}
+	return true
+}
+
+func (h *hashcashServer) issueChallenge(w http.ResponseWriter, ip string) {
+	challenge := make([]byte, 16)
+	if _, err := rand.Read(challenge); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "generate challenge: " + err.Error()})
+		return
+	}
+
+	h.mu.Lock()
+	h.pending[ip] = pendingChallenge{challenge: challenge, issuedAt: time.Now()}
+	h.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusPaymentRequired)
+	json.NewEncoder(w).Encode(hashcashChallengeResponse{
+		Challenge:  hex.EncodeToString(challenge),
+		Difficulty: h.difficulty,
+	})
+}
+
+// verify checks nonceHex against whichever challenge was last issued to
+// ip: the proof must have at least h.difficulty leading zero bits and
+// must not already be in the recently-accepted LRU (a replay of the same
+// solved challenge). A verified proof is consumed — pending[ip] is
+// cleared so a second submission of the same nonce fails as a replay
+// rather than succeeding again.
+func (h *hashcashServer) verify(ip, nonceHex string) bool {
+	nonce, err := hex.DecodeString(nonceHex)
+	if err != nil {
+		return false
+	}
+
+	h.mu.Lock()
+	pc, ok := h.pending[ip]
+	if ok {
+		delete(h.pending, ip)
+	}
+	h.mu.Unlock()
+	if !ok || time.Since(pc.issuedAt) > hashcashChallengeTTL {
+		return false
+	}
+
+	sum := sha256.Sum256(append(append([]byte{}, pc.challenge...), nonce...))
+	if leadingZeroBits(sum[:]) < h.difficulty {
+		return false
+	}
+
+	replayKey := hex.EncodeToString(pc.challenge) + ":" + nonceHex
+	return h.markAccepted(replayKey)
+}
+
+// markAccepted returns false if key was already in the accepted LRU
+// (a replay), otherwise records it and evicts the oldest entry once the
+// LRU is over capacity.
+func (h *hashcashServer) markAccepted(key string) bool {
+	h.acceptedMu.Lock()
+	defer h.acceptedMu.Unlock()
+
+	if _, seen := h.accepted[key]; seen {
+		return false
+	}
+	el := h.acceptedLL.PushFront(key)
+	h.accepted[key] = el
+	if h.acceptedLL.Len() > hashcashAcceptedCapacity {
+		if oldest := h.acceptedLL.Back(); oldest != nil {
+			h.acceptedLL.Remove(oldest)
+			delete(h.accepted, oldest.Value.(string))
+		}
+	}
+	return true
+}
+
+// leadingZeroBits counts how many leading bits of b are zero.
+func leadingZeroBits(b []byte) int {
+	count := 0
+	for _, by := range b {
+		if by == 0 {
+			count += 8
+			continue
+		}
+		count += bits.LeadingZeros8(by)
+		break
+	}
+	return count
+}
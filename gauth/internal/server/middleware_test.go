@@ -0,0 +1,64 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nicksrandall/gauth/internal/config"
+)
+
+func TestAuthMiddleware_HashcashGuardsEveryRoute(t *testing.T) {
+	authCfg := config.APIAuthConfig{HashcashEnabled: true, HashcashDifficulty: 1}
+	cfg := &config.Config{}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := authMiddleware(cfg, authCfg, next)
+
+	for _, path := range []string{"/api/token", "/oauth2/token", "/api/fcm/webhook", "/metrics", "/"} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "198.51.100.1:1234"
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusPaymentRequired {
+			t.Errorf("path %s: expected 402 challenge without a hashcash proof, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestAuthMiddleware_RequireKeyDeniesEveryRoute(t *testing.T) {
+	cfg := &config.Config{APISecret: "s3cret"}
+	authCfg := config.APIAuthConfig{}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := authMiddleware(cfg, authCfg, next)
+
+	for _, path := range []string{"/api/token", "/oauth2/token", "/api/fcm/webhook", "/metrics", "/"} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "198.51.100.1:1234"
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("path %s: expected 401 without a bearer token, got %d", path, rec.Code)
+		}
+	}
+}
+
+func TestAuthMiddleware_NoAuthConfiguredAllowsThrough(t *testing.T) {
+	cfg := &config.Config{}
+	authCfg := config.APIAuthConfig{}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := authMiddleware(cfg, authCfg, next)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.RemoteAddr = "198.51.100.1:1234"
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 when no auth mode is configured at all, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,387 @@
+// Package server — transparent MITM forward-proxy mode.
+// Instead of reverse-proxying accounts.google.com under /glogin and rewriting
+// response bodies, this mode runs gauth as an HTTP CONNECT proxy: it mints a
+// leaf certificate on the fly for each upstream host (signed by a local CA
+// generated on first run), terminates TLS itself, captures the oauth_token
+// Set-Cookie in the clear, then re-encrypts to the client. No URL/cookie
+// rewriting is needed because the client talks to the real Google origin.
+// Only hosts in the sign-in flow's allowlist (config.ProxyConfig's
+// UpstreamHost/StaticAllowlist — the same set the rewrite proxy trusts) get
+// TLS termination; a CONNECT to anything else is tunneled through blind,
+// since trusting the generated CA once would otherwise make this a
+// general-purpose TLS-breaking proxy for every site the client visits.
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nicksrandall/gauth/internal/config"
+)
+
+const (
+	mitmCACertFile = "mitm_ca_cert.pem"
+	mitmCAKeyFile  = "mitm_ca_key.pem"
+)
+
+// mitmCA holds the local CA used to mint leaf certs on the fly.
+type mitmCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+
+	mu    sync.Mutex
+	leafs map[string]*tls.Certificate
+}
+
+// NewMITMProxy returns an http.Handler that runs gauth as a CONNECT forward
+// proxy. CONNECT targets on the sign-in allowlist (accounts.google.com and
+// the other Google auth hosts configured in config.ProxyConfig) are
+// TLS-intercepted to capture the oauth_token cookie; everything else is
+// tunneled through without decryption. On first call it generates and caches
+// a local CA keypair next to the config file; the user (or a headless
+// Chromium gauth spawns) must trust this CA once for TLS interception to
+// work.
+func NewMITMProxy(cfg *config.Config, state *ProxyState) (http.Handler, error) {
+	ca, err := loadOrCreateMITMCA()
+	if err != nil {
+		return nil, fmt.Errorf("mitm ca: %w", err)
+	}
+	proxyCfg := config.LoadLayered("", config.ProxyConfig{})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "mitm proxy only handles CONNECT", http.StatusMethodNotAllowed)
+			return
+		}
+		handleConnect(w, r, cfg, state, ca, proxyCfg)
+	}), nil
+}
+
+// isMITMAllowedHost reports whether host may be TLS-intercepted: it's the
+// sign-in upstream itself or one of the other Google auth hosts the
+// rewrite proxy already trusts (see config.ProxyConfig.IsAllowedStaticDomain).
+func isMITMAllowedHost(host string, proxyCfg config.ProxyConfig) bool {
+	return host == proxyCfg.UpstreamHost || proxyCfg.IsAllowedStaticDomain(host)
+}
+
+// CACertPEM returns the PEM-encoded CA certificate so callers can present it
+// to the user (or install it into a headless Chromium's trust store) before
+// starting the proxy.
+func CACertPEM() ([]byte, error) {
+	ca, err := loadOrCreateMITMCA()
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), nil
+}
+
+func loadOrCreateMITMCA() (*mitmCA, error) {
+	dir := filepath.Dir(config.ConfigPath())
+	certPath := filepath.Join(dir, mitmCACertFile)
+	keyPath := filepath.Join(dir, mitmCAKeyFile)
+
+	if certPEM, err := os.ReadFile(certPath); err == nil {
+		if keyPEM, err := os.ReadFile(keyPath); err == nil {
+			ca, err := parseMITMCA(certPEM, keyPEM)
+			if err == nil {
+				return ca, nil
+			}
+			log.Printf("[mitm] cached CA unreadable, regenerating: %v", err)
+		}
+	}
+
+	ca, certPEM, keyPEM, err := generateMITMCA()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create config dir: %w", err)
+	}
+	if err := os.WriteFile(certPath, certPEM, 0644); err != nil {
+		return nil, fmt.Errorf("write ca cert: %w", err)
+	}
+	if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		return nil, fmt.Errorf("write ca key: %w", err)
+	}
+	log.Printf("[mitm] generated new CA at %s — trust it once in your browser/OS", certPath)
+	return ca, nil
+}
+
+func generateMITMCA() (*mitmCA, []byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("generate ca key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("generate serial: %w", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "gauth local MITM CA", Organization: []string{"gauth"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("create ca cert: %w", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("parse ca cert: %w", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("marshal ca key: %w", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	return &mitmCA{cert: cert, key: key, leafs: make(map[string]*tls.Certificate)}, certPEM, keyPEM, nil
+}
+
+func parseMITMCA(certPEM, keyPEM []byte) (*mitmCA, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, fmt.Errorf("no PEM block in ca cert")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse ca cert: %w", err)
+	}
+
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, fmt.Errorf("no PEM block in ca key")
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse ca key: %w", err)
+	}
+
+	return &mitmCA{cert: cert, key: key, leafs: make(map[string]*tls.Certificate)}, nil
+}
+
+// leafFor returns a TLS certificate for host, minting and caching it on first use.
+func (ca *mitmCA) leafFor(host string) (*tls.Certificate, error) {
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+
+	if leaf, ok := ca.leafs[host]; ok {
+		return leaf, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate leaf key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("generate serial: %w", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(1, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, fmt.Errorf("mint leaf cert for %s: %w", host, err)
+	}
+
+	leaf := &tls.Certificate{
+		Certificate: [][]byte{der, ca.cert.Raw},
+		PrivateKey:  key,
+	}
+	ca.leafs[host] = leaf
+	return leaf, nil
+}
+
+// handleConnect hijacks a CONNECT request. For an allowlisted host it
+// terminates TLS locally using a minted leaf cert and pipes the plaintext
+// HTTP traffic through a handler that looks for the oauth_token cookie
+// before re-encrypting to the client; for anything else it tunnels the
+// connection through blind, exactly like a normal forward proxy, since this
+// process's generated CA is only meant to intercept the Google sign-in flow.
+func handleConnect(w http.ResponseWriter, r *http.Request, cfg *config.Config, state *ProxyState, ca *mitmCA, proxyCfg config.ProxyConfig) {
+	host := r.URL.Hostname()
+	if host == "" {
+		host, _, _ = net.SplitHostPort(r.Host)
+	}
+	if host == "" {
+		host = r.Host
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	clientConn, _, err := hijacker.Hijack()
+	if err != nil {
+		log.Printf("[mitm] hijack failed: %v", err)
+		return
+	}
+	defer clientConn.Close()
+
+	if _, err := clientConn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		return
+	}
+
+	if !isMITMAllowedHost(host, proxyCfg) {
+		tunnelPassthrough(clientConn, r.Host, host)
+		return
+	}
+
+	leaf, err := ca.leafFor(host)
+	if err != nil {
+		log.Printf("[mitm] %v", err)
+		return
+	}
+
+	tlsConn := tls.Server(clientConn, &tls.Config{Certificates: []tls.Certificate{*leaf}})
+	defer tlsConn.Close()
+	if err := tlsConn.Handshake(); err != nil {
+		log.Printf("[mitm] TLS handshake with client failed for %s: %v", host, err)
+		return
+	}
+
+	// Serve plaintext HTTP over the now-decrypted client connection, forwarding
+	// each request upstream over real TLS and capturing oauth_token along the way.
+	captureHandler := mitmUpstreamHandler(cfg, state, host)
+	if err := http.Serve(&singleConnListener{conn: tlsConn}, captureHandler); err != nil && err != io.EOF {
+		log.Printf("[mitm] session for %s ended: %v", host, err)
+	}
+}
+
+// tunnelPassthrough dials hostPort (the original CONNECT target, already
+// including its port) and blindly pipes bytes in both directions, without
+// ever decrypting — used for any CONNECT target outside the sign-in
+// allowlist so the proxy still works as a normal tunnel for the rest of the
+// client's traffic.
+func tunnelPassthrough(clientConn net.Conn, hostPort, host string) {
+	upstreamConn, err := net.DialTimeout("tcp", hostPort, 10*time.Second)
+	if err != nil {
+		log.Printf("[mitm] dial %s: %v", hostPort, err)
+		return
+	}
+	defer upstreamConn.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		io.Copy(upstreamConn, clientConn)
+	}()
+	go func() {
+		defer wg.Done()
+		io.Copy(clientConn, upstreamConn)
+	}()
+	wg.Wait()
+}
+
+// mitmUpstreamHandler forwards decrypted requests to the real host over TLS,
+// watching Set-Cookie headers for oauth_token exactly like googleProxyHandler
+// does, but without any URL rewriting since the client already addressed the
+// real origin.
+func mitmUpstreamHandler(cfg *config.Config, state *ProxyState, host string) http.Handler {
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstream := "https://" + host + r.URL.RequestURI()
+		proxyReq, err := http.NewRequest(r.Method, upstream, r.Body)
+		if err != nil {
+			http.Error(w, "proxy error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		proxyReq.Header = r.Header.Clone()
+		proxyReq.Host = host
+
+		resp, err := client.Do(proxyReq)
+		if err != nil {
+			http.Error(w, "upstream error: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+
+		for _, sc := range resp.Header.Values("Set-Cookie") {
+			if strings.Contains(sc, "oauth_token=") {
+				for _, part := range strings.Split(sc, ";") {
+					part = strings.TrimSpace(part)
+					if token, ok := strings.CutPrefix(part, "oauth_token="); ok && token != "" && !state.IsCaptured() {
+						log.Printf("[mitm] 🎯 Captured oauth_token from %s (len=%d)", host, len(token))
+						state.SetToken(token)
+						go exchangeToken(cfg, state, token)
+					}
+				}
+			}
+			w.Header().Add("Set-Cookie", sc)
+		}
+
+		for key, vals := range resp.Header {
+			if strings.EqualFold(key, "Set-Cookie") {
+				continue
+			}
+			for _, v := range vals {
+				w.Header().Add(key, v)
+			}
+		}
+
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+	})
+}
+
+// singleConnListener adapts a single net.Conn to a net.Listener so we can
+// reuse http.Serve to speak HTTP/1.1 over the connection we've already
+// hijacked and TLS-terminated ourselves.
+type singleConnListener struct {
+	conn net.Conn
+}
+
+func (l *singleConnListener) Accept() (net.Conn, error) {
+	if l.conn == nil {
+		return nil, io.EOF
+	}
+	c := l.conn
+	l.conn = nil
+	return c, nil
+}
+
+func (l *singleConnListener) Close() error   { return nil }
+func (l *singleConnListener) Addr() net.Addr { return l.conn.LocalAddr() }
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/nicksrandall/gauth/internal/config"
+)
+
+func TestIsMITMAllowedHost(t *testing.T) {
+	proxyCfg := config.DefaultProxyConfig()
+
+	allowed := []string{
+		"accounts.google.com",  // the configured UpstreamHost
+		"ssl.gstatic.com",      // explicit StaticAllowlist entry
+		"myaccount.google.com", // explicit StaticAllowlist entry
+		"foo.google.com",       // .google.com suffix match
+	}
+	for _, host := range allowed {
+		if !isMITMAllowedHost(host, proxyCfg) {
+			t.Errorf("expected %s to be allowed for TLS interception", host)
+		}
+	}
+
+	denied := []string{
+		"evil.example.com",
+		"accounts-google.com.attacker.net",
+		"bank.com",
+	}
+	for _, host := range denied {
+		if isMITMAllowedHost(host, proxyCfg) {
+			t.Errorf("expected %s to be denied TLS interception and blind-tunneled instead", host)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/nicksrandall/gauth/internal/auth"
+	"github.com/nicksrandall/gauth/internal/config"
+)
+
+// OAuth2TokenResponse is the RFC 6749 token endpoint response body.
+type OAuth2TokenResponse struct {
+	AccessToken string `json:"access_token,omitempty"`
+	TokenType   string `json:"token_type,omitempty"`
+	ExpiresIn   int64  `json:"expires_in,omitempty"`
+	Scope       string `json:"scope,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// UserInfoResponse is the OIDC userinfo endpoint response body.
+type UserInfoResponse struct {
+	Email     string `json:"email,omitempty"`
+	FirstName string `json:"given_name,omitempty"`
+	LastName  string `json:"family_name,omitempty"`
+	AccountID string `json:"sub,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// IntrospectResponse is the RFC 7662 introspection endpoint response body.
+type IntrospectResponse struct {
+	Active bool   `json:"active"`
+	Scope  string `json:"scope,omitempty"`
+	Exp    int64  `json:"exp,omitempty"`
+	Sub    string `json:"sub,omitempty"`
+}
+
+// registerOAuth2Routes adds OAuth2/OIDC-shaped routes alongside the ad-hoc
+// /api/token ones, so clients that already speak OAuth2 (golang.org/x/oauth2
+// and friends) can use gauth as a local token broker without any
+// gauth-specific client code.
+func registerOAuth2Routes(mux *http.ServeMux, cfg *config.Config) {
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		issuer := issuerURL(r)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"issuer":                                issuer,
+			"token_endpoint":                        issuer + "/oauth2/token",
+			"userinfo_endpoint":                     issuer + "/oauth2/userinfo",
+			"introspection_endpoint":                issuer + "/oauth2/introspect",
+			"grant_types_supported":                 []string{"client_credentials", "refresh_token"},
+			"token_endpoint_auth_methods_supported": []string{"none", "client_secret_post"},
+			"subject_types_supported":               []string{"public"},
+			"id_token_signing_alg_values_supported": []string{"none"},
+		})
+	})
+
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, OAuth2TokenResponse{Error: "unsupported_grant_type"})
+			return
+		}
+		if !checkBearerSecret(cfg, w, r) {
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			writeJSON(w, http.StatusBadRequest, OAuth2TokenResponse{Error: "invalid_request"})
+			return
+		}
+
+		switch r.FormValue("grant_type") {
+		case "client_credentials", "refresh_token":
+		default:
+			writeJSON(w, http.StatusBadRequest, OAuth2TokenResponse{Error: "unsupported_grant_type"})
+			return
+		}
+
+		reqCfg := accountConfigForRequest(cfg, r)
+		if !reqCfg.HasMasterToken() {
+			writeJSON(w, http.StatusUnauthorized, OAuth2TokenResponse{Error: "invalid_client"})
+			return
+		}
+
+		scope := r.FormValue("scope")
+		appPackage := "com.google.android.gms"
+		appSig := auth.GoogleSig
+		if app, ok := auth.KnownApps[scope]; ok {
+			appPackage = app.Package
+			scope = app.Scope
+		}
+		if scope == "" {
+			writeJSON(w, http.StatusBadRequest, OAuth2TokenResponse{Error: "invalid_scope"})
+			return
+		}
+
+		resp, err := auth.GetServiceToken(reqCfg, scope, appPackage, appSig)
+		if err != nil || resp.Auth == "" {
+			writeJSON(w, http.StatusBadGateway, OAuth2TokenResponse{Error: "temporarily_unavailable"})
+			return
+		}
+
+		var expiresIn int64
+		if resp.Expiry > 0 {
+			expiresIn = resp.Expiry - time.Now().Unix()
+		}
+
+		writeJSON(w, http.StatusOK, OAuth2TokenResponse{
+			AccessToken: resp.Auth,
+			TokenType:   "Bearer",
+			ExpiresIn:   expiresIn,
+			Scope:       resp.GrantedScopes,
+		})
+	})
+
+	mux.HandleFunc("/oauth2/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		reqCfg := accountConfigForRequest(cfg, r)
+		if !reqCfg.HasMasterToken() {
+			writeJSON(w, http.StatusUnauthorized, UserInfoResponse{Error: "not logged in"})
+			return
+		}
+		info, ok := auth.UserInfo(reqCfg.Email)
+		if !ok {
+			writeJSON(w, http.StatusNotFound, UserInfoResponse{Error: "no cached token yet for " + reqCfg.Email})
+			return
+		}
+		writeJSON(w, http.StatusOK, UserInfoResponse{
+			Email:     info.Email,
+			FirstName: info.FirstName,
+			LastName:  info.LastName,
+			AccountID: info.AccountID,
+		})
+	})
+
+	mux.HandleFunc("/oauth2/introspect", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, IntrospectResponse{})
+			return
+		}
+		if !checkBearerSecret(cfg, w, r) {
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			writeJSON(w, http.StatusBadRequest, IntrospectResponse{})
+			return
+		}
+
+		info, ok := auth.IntrospectToken(r.FormValue("token"))
+		if !ok || info.Expiry <= time.Now().Unix() {
+			writeJSON(w, http.StatusOK, IntrospectResponse{Active: false})
+			return
+		}
+		writeJSON(w, http.StatusOK, IntrospectResponse{
+			Active: true,
+			Scope:  info.Scope,
+			Exp:    info.Expiry,
+			Sub:    info.Email,
+		})
+	})
+}
+
+// checkBearerSecret enforces cfg.APISecret, if set, as a bearer token on
+// /oauth2/token and /oauth2/introspect — both can mint or reveal usable
+// tokens, unlike the read-only /oauth2/userinfo and well-known document. An
+// empty APISecret means protection isn't configured, so every request
+// passes, matching the rest of this server's opt-in-only security posture.
+// The comparison itself reuses apiKeyAllowed (middleware.go), which checks
+// candidates with subtle.ConstantTimeCompare rather than Go's plain `!=`,
+// so this endpoint doesn't leak the secret one byte at a time over timing.
+func checkBearerSecret(cfg *config.Config, w http.ResponseWriter, r *http.Request) bool {
+	if cfg.APISecret == "" {
+		return true
+	}
+	key, ok := bearerKey(r)
+	if !ok || !apiKeyAllowed(cfg, config.APIAuthConfig{}, key) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid or missing bearer token"})
+		return false
+	}
+	return true
+}
+
+// issuerURL derives the OAuth2 issuer from the incoming request rather than
+// a hardcoded config value, since gauth runs on whatever host/port the user
+// picked and may be reached over http or the auto-HTTPS listener.
+func issuerURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/nicksrandall/gauth/internal/config"
+)
+
+// ProfileInfo describes one signed-in account for GET /api/profiles —
+// deliberately omits MasterToken/SecurityToken, since this is meant to be
+// safe to expose to whatever's driving the `gauth serve` web UI.
+type ProfileInfo struct {
+	Email     string `json:"email"`
+	AndroidID string `json:"android_id,omitempty"`
+	Default   bool   `json:"default"`
+}
+
+// ProfileUseRequest is the JSON body for POST /api/profiles.
+type ProfileUseRequest struct {
+	Email string `json:"email"`
+}
+
+// registerProfileRoutes adds GET/POST/DELETE /api/profiles, the HTTP
+// counterpart to `gauth accounts list|use|remove`: list every signed-in
+// account, make one the default, or forget one. Adding a new account isn't
+// exposed here — an account only becomes real once `gauth login` has
+// exchanged real credentials for it, which isn't something a bare email
+// POSTed to this endpoint can do.
+func registerProfileRoutes(mux *http.ServeMux, cfg *config.Config) {
+	mux.HandleFunc("/api/profiles", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			store := config.LoadAccountStore()
+			emails := make([]string, 0, len(store.Accounts))
+			for email := range store.Accounts {
+				emails = append(emails, email)
+			}
+			sort.Strings(emails)
+
+			profiles := make([]ProfileInfo, 0, len(emails))
+			for _, email := range emails {
+				acct := store.Accounts[email]
+				profiles = append(profiles, ProfileInfo{
+					Email:     acct.Email,
+					AndroidID: acct.AndroidID,
+					Default:   email == store.Default,
+				})
+			}
+			writeJSON(w, http.StatusOK, profiles)
+
+		case http.MethodPost:
+			var req ProfileUseRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON: " + err.Error()})
+				return
+			}
+			store := config.LoadAccountStore()
+			if err := store.SetDefault(req.Email); err != nil {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+				return
+			}
+			if err := store.Save(); err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]string{"default": req.Email})
+
+		case http.MethodDelete:
+			email := r.URL.Query().Get("email")
+			if email == "" {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "email query parameter is required"})
+				return
+			}
+			store := config.LoadAccountStore()
+			store.Remove(email)
+			if err := store.Save(); err != nil {
+				writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]string{"removed": email})
+
+		default:
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "use GET, POST, or DELETE"})
+		}
+	})
+}
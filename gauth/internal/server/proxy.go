@@ -9,9 +9,11 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/nicksrandall/gauth/internal/auth"
 	"github.com/nicksrandall/gauth/internal/config"
@@ -26,12 +28,42 @@ type ProxyState struct {
 	email      string
 	error      string
 	listeners  []chan struct{}
+
+	store SessionStore // optional; set via SetStore to persist captured sessions
 }
 
 func NewProxyState() *ProxyState {
 	return &ProxyState{}
 }
 
+// SetStore attaches a SessionStore so future captures are persisted (and
+// multiple accounts can be remembered across restarts) instead of living
+// only in this process's memory.
+func (s *ProxyState) SetStore(store SessionStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = store
+}
+
+// Accounts returns every session remembered by the attached store, or an
+// empty slice if no store is configured. Used by /api/login-status so the UI
+// can offer account switching instead of just the single most-recent login.
+func (s *ProxyState) Accounts() []SessionRecord {
+	s.mu.Lock()
+	store := s.store
+	s.mu.Unlock()
+
+	if store == nil {
+		return nil
+	}
+	accounts, err := store.List()
+	if err != nil {
+		log.Printf("[proxy] list sessions: %v", err)
+		return nil
+	}
+	return accounts
+}
+
 func (s *ProxyState) SetToken(token string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -58,6 +90,14 @@ func (s *ProxyState) IsCaptured() bool {
 	return s.captured
 }
 
+// Result returns the captured email and any exchange error, for the
+// /api/login-status polling endpoint.
+func (s *ProxyState) Result() (email, errMsg string, captured bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.email, s.error, s.captured
+}
+
 func (s *ProxyState) Subscribe() chan struct{} {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -69,8 +109,11 @@ func (s *ProxyState) Subscribe() chan struct{} {
 	return ch
 }
 
-// googleProxyHandler handles /glogin/* → accounts.google.com/*
-func googleProxyHandler(cfg *config.Config, state *ProxyState, port int) http.Handler {
+// googleProxyHandler handles /glogin/* → accounts.google.com/* (or whatever
+// upstream_host/mount_path the layered proxy config points at).
+func googleProxyHandler(cfg *config.Config, state *ProxyState, port int, scheme string) http.Handler {
+	proxyCfg := config.LoadLayered("", config.ProxyConfig{})
+
 	// Build the JS bridge once
 	jsBridge := login.BuildJSBridge(cfg)
 
@@ -82,7 +125,7 @@ func googleProxyHandler(cfg *config.Config, state *ProxyState, port int) http.Ha
 		var _origClose = window.mm.closeView;
 		window.mm.closeView = function() {
 			console.log('[gauth-proxy] closeView called, notifying server...');
-			fetch('http://localhost:%d/api/proxy-extract', {method:'POST'})
+			fetch('%s://localhost:%d/api/proxy-extract', {method:'POST'})
 				.then(function(r) { return r.json(); })
 				.then(function(d) {
 					if (d.success) {
@@ -95,7 +138,7 @@ func googleProxyHandler(cfg *config.Config, state *ProxyState, port int) http.Ha
 	}
 	console.log('[gauth-proxy] Bridge + closeView override ready');
 })();
-`, port)
+`, scheme, port)
 
 	transport := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: false},
@@ -108,20 +151,28 @@ func googleProxyHandler(cfg *config.Config, state *ProxyState, port int) http.Ha
 	}
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Strip /glogin prefix to get the path on accounts.google.com
-		path := strings.TrimPrefix(r.URL.Path, "/glogin")
+		// Strip the configured mount prefix to get the path on the upstream host.
+		path := strings.TrimPrefix(r.URL.Path, proxyCfg.MountPath)
 		if path == "" {
 			path = "/"
 		}
 
 		// Build upstream URL
-		upstream := "https://accounts.google.com" + path
+		upstream := "https://" + proxyCfg.UpstreamHost + path
 		if r.URL.RawQuery != "" {
 			upstream += "?" + r.URL.RawQuery
 		}
 
 		log.Printf("[proxy] %s %s → %s", r.Method, r.URL.Path, upstream)
 
+		// Long-poll signalers and WebSocket upgrades (Google's embedded setup
+		// flow increasingly opens one of these) can't go through the
+		// buffer-and-rewrite path below — hijack and splice the raw conns.
+		if isUpgradeRequest(r) {
+			hijackAndPipe(w, r, proxyCfg.UpstreamHost+":443", proxyCfg.HostHeaderFor(proxyCfg.UpstreamHost))
+			return
+		}
+
 		// Create upstream request
 		var body io.Reader
 		if r.Body != nil {
@@ -148,15 +199,16 @@ func googleProxyHandler(cfg *config.Config, state *ProxyState, port int) http.Ha
 		// Override critical headers to spoof Android
 		proxyReq.Header.Set("User-Agent", cfg.UserAgent())
 		proxyReq.Header.Set("Accept-Encoding", "gzip") // we'll decompress ourselves
-		proxyReq.Host = "accounts.google.com"
+		proxyReq.Host = proxyCfg.HostHeaderFor(proxyCfg.UpstreamHost)
 
-		// Rewrite Origin/Referer to point to Google
+		// Rewrite Origin/Referer to point to the upstream host
+		upstreamOrigin := "https://" + proxyCfg.UpstreamHost
 		if ref := proxyReq.Header.Get("Referer"); ref != "" {
-			ref = strings.Replace(ref, fmt.Sprintf("http://localhost:%d/glogin", port), "https://accounts.google.com", 1)
+			ref = strings.Replace(ref, fmt.Sprintf("%s://localhost:%d%s", scheme, port, proxyCfg.MountPath), upstreamOrigin, 1)
 			proxyReq.Header.Set("Referer", ref)
 		}
 		if origin := proxyReq.Header.Get("Origin"); origin != "" {
-			proxyReq.Header.Set("Origin", "https://accounts.google.com")
+			proxyReq.Header.Set("Origin", upstreamOrigin)
 		}
 
 		// Forward request
@@ -189,28 +241,32 @@ func googleProxyHandler(cfg *config.Config, state *ProxyState, port int) http.Ha
 				}
 			}
 
-			// Rewrite cookie domain for localhost
+			// Rewrite cookie domain for localhost. Secure/SameSite only need
+			// stripping when we're serving plain HTTP; under TLS the browser
+			// will happily accept them on our own https://localhost origin.
 			sc = removeCookieAttr(sc, "Domain")
-			sc = removeCookieAttr(sc, "Secure")
-			sc = removeCookieAttr(sc, "SameSite")
-			sc = strings.Replace(sc, "; Secure", "", 1)
+			if scheme != "https" {
+				sc = removeCookieAttr(sc, "Secure")
+				sc = removeCookieAttr(sc, "SameSite")
+				sc = strings.Replace(sc, "; Secure", "", 1)
+			}
 			w.Header().Add("Set-Cookie", sc)
 		}
 
 		// === Process response headers ===
 		for key, vals := range resp.Header {
 			lower := strings.ToLower(key)
-			// Skip headers we handle ourselves
-			if lower == "set-cookie" || lower == "content-security-policy" ||
-				lower == "x-frame-options" || lower == "content-length" ||
-				lower == "content-encoding" || lower == "strict-transport-security" ||
-				lower == "x-content-type-options" {
+			// Skip headers we always handle ourselves, plus whatever the
+			// config says to strip (CSP/HSTS/etc. that would otherwise break
+			// the rewritten page running under our localhost origin).
+			if lower == "set-cookie" || lower == "content-length" || lower == "content-encoding" ||
+				headerListContains(proxyCfg.StripResponseHeaders, key) {
 				continue
 			}
 			// Rewrite Location headers for redirects
 			if lower == "location" {
 				for _, v := range vals {
-					v = rewriteGoogleURL(v, port)
+					v = rewriteGoogleURL(v, proxyCfg, port, scheme)
 					w.Header().Add(key, v)
 				}
 				continue
@@ -248,7 +304,7 @@ func googleProxyHandler(cfg *config.Config, state *ProxyState, port int) http.Ha
 			content := string(bodyBytes)
 
 			// Rewrite Google URLs to go through proxy
-			content = rewriteBodyURLs(content, port)
+			content = rewriteBodyURLs(content, proxyCfg, port, scheme)
 
 			// Inject JS bridge into HTML pages
 			if isHTML {
@@ -266,9 +322,17 @@ func googleProxyHandler(cfg *config.Config, state *ProxyState, port int) http.Ha
 			w.WriteHeader(resp.StatusCode)
 			fmt.Fprint(w, content)
 		} else {
-			// Binary content — pass through unchanged
+			// Binary content (images, fonts, long-poll bodies) — stream it
+			// through a pipe instead of io.ReadAll-ing the whole thing first,
+			// flushing after every chunk so it actually reaches the client
+			// incrementally.
 			w.WriteHeader(resp.StatusCode)
-			io.Copy(w, reader)
+			pr, pw := io.Pipe()
+			go func() {
+				_, copyErr := io.Copy(pw, reader)
+				pw.CloseWithError(copyErr)
+			}()
+			streamCopy(w, pr)
 		}
 	})
 }
@@ -277,6 +341,8 @@ func googleProxyHandler(cfg *config.Config, state *ProxyState, port int) http.Ha
 // Path format: /gproxy/{domain}/{path}
 // e.g., /gproxy/ssl.gstatic.com/accounts/... → https://ssl.gstatic.com/accounts/...
 func staticProxyHandler(cfg *config.Config, port int) http.Handler {
+	proxyCfg := config.LoadLayered("", config.ProxyConfig{})
+
 	transport := &http.Transport{}
 	client := &http.Client{
 		Transport: transport,
@@ -296,32 +362,14 @@ func staticProxyHandler(cfg *config.Config, port int) http.Handler {
 		domain := path[:slashIdx]
 		rest := path[slashIdx:]
 
-		// Only allow known Google domains
-		allowed := map[string]bool{
-			"ssl.gstatic.com":                 true,
-			"www.gstatic.com":                 true,
-			"fonts.gstatic.com":               true,
-			"fonts.googleapis.com":            true,
-			"apis.google.com":                 true,
-			"www.google.com":                  true,
-			"play.google.com":                 true,
-			"lh3.googleusercontent.com":       true,
-			"accounts.youtube.com":            true,
-			"myaccount.google.com":            true,
-			"ogs.google.com":                  true,
-			"clients1.google.com":             true,
-			"signaler-pa.clients6.google.com": true,
-			"content-autofill.googleapis.com": true,
-		}
-		if !allowed[domain] {
-			// Allow any *.google.com or *.gstatic.com or *.googleapis.com
-			if !strings.HasSuffix(domain, ".google.com") &&
-				!strings.HasSuffix(domain, ".gstatic.com") &&
-				!strings.HasSuffix(domain, ".googleapis.com") &&
-				!strings.HasSuffix(domain, ".googleusercontent.com") {
-				http.Error(w, "domain not allowed: "+domain, 403)
-				return
-			}
+		if !proxyCfg.IsAllowedStaticDomain(domain) {
+			http.Error(w, "domain not allowed: "+domain, 403)
+			return
+		}
+
+		if isUpgradeRequest(r) {
+			hijackAndPipe(w, r, domain+":443", proxyCfg.HostHeaderFor(domain))
+			return
 		}
 
 		upstream := "https://" + domain + rest
@@ -344,7 +392,7 @@ func staticProxyHandler(cfg *config.Config, port int) http.Handler {
 				proxyReq.Header.Add(key, v)
 			}
 		}
-		proxyReq.Host = domain
+		proxyReq.Host = proxyCfg.HostHeaderFor(domain)
 
 		resp, err := client.Do(proxyReq)
 		if err != nil {
@@ -354,9 +402,7 @@ func staticProxyHandler(cfg *config.Config, port int) http.Handler {
 		defer resp.Body.Close()
 
 		for key, vals := range resp.Header {
-			lower := strings.ToLower(key)
-			if lower == "content-security-policy" || lower == "x-frame-options" ||
-				lower == "strict-transport-security" {
+			if headerListContains(proxyCfg.StripResponseHeaders, key) {
 				continue
 			}
 			for _, v := range vals {
@@ -365,7 +411,7 @@ func staticProxyHandler(cfg *config.Config, port int) http.Handler {
 		}
 
 		w.WriteHeader(resp.StatusCode)
-		io.Copy(w, resp.Body)
+		streamCopy(w, resp.Body)
 	})
 }
 
@@ -394,6 +440,26 @@ func exchangeToken(cfg *config.Config, state *ProxyState, oauthToken string) {
 		state.SetResult("", "save failed: "+err.Error())
 		return
 	}
+	if err := cfg.SaveAccount(); err != nil {
+		state.SetResult("", "save account failed: "+err.Error())
+		return
+	}
+
+	state.mu.Lock()
+	store := state.store
+	state.mu.Unlock()
+	if store != nil {
+		rec := SessionRecord{
+			Email:       resp.Email,
+			OAuthToken:  oauthToken,
+			MasterToken: masterToken,
+			CapturedAt:  time.Now(),
+			DeviceID:    cfg.AndroidID,
+		}
+		if err := store.Save(rec); err != nil {
+			log.Printf("[proxy] failed to persist session for %s: %v", resp.Email, err)
+		}
+	}
 
 	log.Printf("[proxy] ✅ Master token saved! Email: %s", resp.Email)
 	state.SetResult(resp.Email, "")
@@ -401,34 +467,25 @@ func exchangeToken(cfg *config.Config, state *ProxyState, oauthToken string) {
 
 // --- URL rewriting helpers ---
 
-func rewriteGoogleURL(u string, port int) string {
-	base := fmt.Sprintf("http://localhost:%d", port)
-	u = strings.Replace(u, "https://accounts.google.com", base+"/glogin", 1)
-	u = strings.Replace(u, "http://accounts.google.com", base+"/glogin", 1)
+func rewriteGoogleURL(u string, proxyCfg config.ProxyConfig, port int, scheme string) string {
+	base := fmt.Sprintf("%s://localhost:%d", scheme, port)
+	upstream := "https://" + proxyCfg.UpstreamHost
+	u = strings.Replace(u, upstream, base+proxyCfg.MountPath, 1)
+	u = strings.Replace(u, "http://"+proxyCfg.UpstreamHost, base+proxyCfg.MountPath, 1)
 	return u
 }
 
-func rewriteBodyURLs(content string, port int) string {
-	base := fmt.Sprintf("http://localhost:%d", port)
-
-	// accounts.google.com → /glogin
-	content = strings.ReplaceAll(content, "https://accounts.google.com", base+"/glogin")
-	content = strings.ReplaceAll(content, "https:\\/\\/accounts.google.com", base+"\\/glogin")
-	content = strings.ReplaceAll(content, "//accounts.google.com", base+"/glogin")
-
-	// Common Google static domains → /gproxy/
-	staticDomains := []string{
-		"ssl.gstatic.com",
-		"www.gstatic.com",
-		"fonts.gstatic.com",
-		"fonts.googleapis.com",
-		"apis.google.com",
-		"ogs.google.com",
-		"play.google.com",
-		"myaccount.google.com",
-		"lh3.googleusercontent.com",
-	}
-	for _, d := range staticDomains {
+func rewriteBodyURLs(content string, proxyCfg config.ProxyConfig, port int, scheme string) string {
+	base := fmt.Sprintf("%s://localhost:%d", scheme, port)
+
+	// upstream_host → mount_path
+	upstream := "https://" + proxyCfg.UpstreamHost
+	content = strings.ReplaceAll(content, upstream, base+proxyCfg.MountPath)
+	content = strings.ReplaceAll(content, strings.ReplaceAll(upstream, "/", "\\/"), strings.ReplaceAll(base+proxyCfg.MountPath, "/", "\\/"))
+	content = strings.ReplaceAll(content, "//"+proxyCfg.UpstreamHost, base+proxyCfg.MountPath)
+
+	// Configured static domains → /gproxy/
+	for _, d := range proxyCfg.StaticRewriteDomains {
 		content = strings.ReplaceAll(content, "https://"+d, base+"/gproxy/"+d)
 		content = strings.ReplaceAll(content, "https:\\/\\/"+d, base+"\\/gproxy\\/"+d)
 		content = strings.ReplaceAll(content, "//"+d, base+"/gproxy/"+d)
@@ -437,6 +494,16 @@ func rewriteBodyURLs(content string, port int) string {
 	return content
 }
 
+// headerListContains reports whether name appears in list, case-insensitively.
+func headerListContains(list []string, name string) bool {
+	for _, h := range list {
+		if strings.EqualFold(h, name) {
+			return true
+		}
+	}
+	return false
+}
+
 func removeCookieAttr(sc string, attr string) string {
 	parts := strings.Split(sc, ";")
 	var result []string
@@ -450,6 +517,90 @@ func removeCookieAttr(sc string, attr string) string {
 	return strings.Join(result, ";")
 }
 
+// --- streaming / upgrade helpers ---
+
+// isUpgradeRequest reports whether r is asking to switch protocols (e.g. a
+// WebSocket handshake, or one of Google's long-poll signaler channels), which
+// needs raw connection splicing instead of the usual buffer-rewrite-forward
+// flow above.
+func isUpgradeRequest(r *http.Request) bool {
+	if r.Header.Get("Upgrade") == "" {
+		return false
+	}
+	for _, v := range strings.Split(r.Header.Get("Connection"), ",") {
+		if strings.EqualFold(strings.TrimSpace(v), "upgrade") {
+			return true
+		}
+	}
+	return false
+}
+
+// hijackAndPipe takes over the client connection, dials upstreamAddr over
+// TLS, forwards the original request line and headers verbatim (with Host
+// rewritten to hostHeader), and then splices the two connections together
+// until either side closes. Used for WebSocket/long-poll upgrades that can't
+// go through net/http's RoundTrip.
+func hijackAndPipe(w http.ResponseWriter, r *http.Request, upstreamAddr, hostHeader string) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "upgrade not supported", http.StatusInternalServerError)
+		return
+	}
+	clientConn, _, err := hj.Hijack()
+	if err != nil {
+		http.Error(w, "hijack failed: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer clientConn.Close()
+
+	sni, _, _ := net.SplitHostPort(upstreamAddr)
+	upstreamConn, err := tls.Dial("tcp", upstreamAddr, &tls.Config{ServerName: sni})
+	if err != nil {
+		log.Printf("[proxy] upgrade dial %s: %v", upstreamAddr, err)
+		return
+	}
+	defer upstreamConn.Close()
+
+	r.Host = hostHeader
+	if err := r.Write(upstreamConn); err != nil {
+		log.Printf("[proxy] upgrade write request: %v", err)
+		return
+	}
+
+	log.Printf("[proxy] 🔀 upgraded %s %s → %s", r.Method, r.URL.Path, upstreamAddr)
+
+	done := make(chan struct{}, 2)
+	splice := func(dst, src net.Conn) {
+		io.Copy(dst, src)
+		done <- struct{}{}
+	}
+	go splice(upstreamConn, clientConn)
+	go splice(clientConn, upstreamConn)
+	<-done
+}
+
+// streamCopy copies src to dst, flushing after every chunk if dst supports
+// it, so piped or directly-forwarded bodies reach the client incrementally
+// instead of waiting for the whole response to arrive.
+func streamCopy(dst io.Writer, src io.Reader) {
+	flusher, canFlush := dst.(http.Flusher)
+	buf := make([]byte, 32*1024)
+	for {
+		n, readErr := src.Read(buf)
+		if n > 0 {
+			if _, err := dst.Write(buf[:n]); err != nil {
+				return
+			}
+			if canFlush {
+				flusher.Flush()
+			}
+		}
+		if readErr != nil {
+			return
+		}
+	}
+}
+
 // loginPageHTML is the landing page for browser-based login.
 const loginPageHTML = `<!DOCTYPE html>
 <html>
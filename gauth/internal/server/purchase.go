@@ -0,0 +1,152 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/nicksrandall/gauth/internal/auth"
+	"github.com/nicksrandall/gauth/internal/config"
+)
+
+// androidPublisherScope is the OAuth2 scope Google Play Developer API calls
+// require.
+const androidPublisherScope = "https://www.googleapis.com/auth/androidpublisher"
+
+// VerifyPurchaseRequest is the JSON body for POST /api/verify-purchase.
+type VerifyPurchaseRequest struct {
+	PackageName         string `json:"package_name"`
+	ProductID           string `json:"product_id"`
+	PurchaseToken       string `json:"purchase_token"`
+	Type                string `json:"type"` // "product" or "subscription"
+	ServiceAccountEmail string `json:"service_account_email,omitempty"`
+}
+
+// VerifyPurchaseResponse is the JSON response for POST /api/verify-purchase.
+type VerifyPurchaseResponse struct {
+	Acknowledged     bool            `json:"acknowledged"`
+	ExpiryTimeMillis string          `json:"expiry_time_millis,omitempty"`
+	Raw              json.RawMessage `json:"raw,omitempty"`
+	Error            string          `json:"error,omitempty"`
+}
+
+// registerPurchaseRoutes adds POST /api/verify-purchase, which looks up a
+// product or subscription purchase token against the Google Play Developer
+// API using an OAuth2 token obtained through the same master-token exchange
+// the rest of gauth uses, instead of a service-account key file.
+func registerPurchaseRoutes(mux *http.ServeMux, cfg *config.Config) {
+	mux.HandleFunc("/api/verify-purchase", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, VerifyPurchaseResponse{Error: "use POST"})
+			return
+		}
+
+		var req VerifyPurchaseRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, VerifyPurchaseResponse{Error: "invalid JSON: " + err.Error()})
+			return
+		}
+		if req.PackageName == "" || req.ProductID == "" || req.PurchaseToken == "" {
+			writeJSON(w, http.StatusBadRequest, VerifyPurchaseResponse{Error: "package_name, product_id and purchase_token are required"})
+			return
+		}
+
+		var resourcePath string
+		switch req.Type {
+		case "", "product":
+			resourcePath = "products"
+		case "subscription":
+			resourcePath = "subscriptions"
+		default:
+			writeJSON(w, http.StatusBadRequest, VerifyPurchaseResponse{Error: `type must be "product" or "subscription"`})
+			return
+		}
+
+		reqCfg := accountConfigForRequest(cfg, r)
+		tokenResp, err := auth.GetOAuth2ForScope(reqCfg, androidPublisherScope, req.ServiceAccountEmail)
+		if err != nil || tokenResp.Auth == "" {
+			writeJSON(w, http.StatusBadGateway, VerifyPurchaseResponse{Error: "fetching verification token: " + errString(err)})
+			return
+		}
+
+		apiURL := fmt.Sprintf(
+			"https://androidpublisher.googleapis.com/androidpublisher/v3/applications/%s/purchases/%s/%s/tokens/%s",
+			url.PathEscape(req.PackageName), resourcePath, url.PathEscape(req.ProductID), url.PathEscape(req.PurchaseToken),
+		)
+		status, body, err := fetchPurchase(apiURL, tokenResp.Auth)
+		if err != nil {
+			writeJSON(w, http.StatusBadGateway, VerifyPurchaseResponse{Error: err.Error()})
+			return
+		}
+
+		if status != http.StatusOK {
+			writeJSON(w, purchaseErrorStatus(status), VerifyPurchaseResponse{
+				Error: fmt.Sprintf("androidpublisher returned status %d", status),
+				Raw:   body,
+			})
+			return
+		}
+
+		var parsed struct {
+			AcknowledgementState int    `json:"acknowledgementState"`
+			ExpiryTimeMillis     string `json:"expiryTimeMillis"`
+		}
+		_ = json.Unmarshal(body, &parsed)
+
+		writeJSON(w, http.StatusOK, VerifyPurchaseResponse{
+			Acknowledged:     parsed.AcknowledgementState == 1,
+			ExpiryTimeMillis: parsed.ExpiryTimeMillis,
+			Raw:              body,
+		})
+	})
+}
+
+// fetchPurchase makes the authenticated GET against the Play Developer API
+// and returns the raw status and body for the caller to interpret.
+func fetchPurchase(apiURL, bearer string) (int, json.RawMessage, error) {
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		return 0, nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+bearer)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("purchase lookup: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, fmt.Errorf("read response: %w", err)
+	}
+	return resp.StatusCode, body, nil
+}
+
+// purchaseErrorStatus maps androidpublisher's error statuses to the status
+// this server replies with: 401/403 pass through as-is (bad/expired
+// verification token or no access to the app), 410 means the purchase
+// token itself is gone (refunded/consumed and since revoked), and anything
+// else becomes a generic 502 since it's an upstream failure, not something
+// the caller did wrong.
+func purchaseErrorStatus(upstream int) int {
+	switch upstream {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusGone:
+		return upstream
+	case http.StatusNotFound:
+		return http.StatusNotFound
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return "empty token in response"
+	}
+	return err.Error()
+}
@@ -0,0 +1,223 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/nicksrandall/gauth/internal/auth"
+	"github.com/nicksrandall/gauth/internal/config"
+)
+
+// RefreshStatus is the current health of one account's stored session, as
+// returned by GET /api/sessions/{email}/status.
+type RefreshStatus struct {
+	Valid       bool      `json:"valid"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	LastRefresh time.Time `json:"last_refresh"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// circuitBreakThreshold is how many consecutive auth failures (401s) it
+// takes before RefreshManager stops retrying an account until a manual
+// refresh is requested.
+const circuitBreakThreshold = 5
+
+type refreshState struct {
+	RefreshStatus
+	consecutiveFailures int
+	circuitOpen         bool
+	nextAttempt         time.Time
+}
+
+// RefreshManager periodically re-validates every stored session's master
+// token by exchanging it for a short-lived service token, so downstream
+// MicroG clients can detect revocation without racing the proxy on every
+// request. Failures back off with jitter; a run of consecutive 401s trips a
+// per-account circuit breaker until /api/sessions/{email}/refresh is called.
+type RefreshManager struct {
+	cfg      *config.Config
+	store    SessionStore
+	interval time.Duration
+
+	mu     sync.Mutex
+	status map[string]*refreshState
+}
+
+// NewRefreshManager creates a manager that checks each stored session every
+// interval (jittered by up to ±20%).
+func NewRefreshManager(cfg *config.Config, store SessionStore, interval time.Duration) *RefreshManager {
+	return &RefreshManager{
+		cfg:      cfg,
+		store:    store,
+		interval: interval,
+		status:   make(map[string]*refreshState),
+	}
+}
+
+// tickInterval is how often Start's loop wakes up to check which accounts
+// are due for a refresh. It's finer than m.interval so per-account
+// exponential backoff can actually take effect between full-interval ticks.
+func (m *RefreshManager) tickInterval() time.Duration {
+	t := m.interval / 4
+	if t < 30*time.Second {
+		t = 30 * time.Second
+	}
+	return t
+}
+
+// Start launches the background refresh loop. It returns immediately; the
+// loop runs until ctx is cancelled.
+func (m *RefreshManager) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(m.tickInterval())
+		defer ticker.Stop()
+		for {
+			m.refreshDue()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (m *RefreshManager) refreshDue() {
+	accounts, err := m.store.List()
+	if err != nil {
+		log.Printf("[refresh] list sessions: %v", err)
+		return
+	}
+	for _, rec := range accounts {
+		m.refreshOne(rec)
+	}
+}
+
+func (m *RefreshManager) refreshOne(rec SessionRecord) {
+	m.mu.Lock()
+	st, ok := m.status[rec.Email]
+	if !ok {
+		st = &refreshState{}
+		m.status[rec.Email] = st
+	}
+	due := st.nextAttempt.IsZero() || !time.Now().Before(st.nextAttempt)
+	circuitOpen := st.circuitOpen
+	m.mu.Unlock()
+
+	if circuitOpen || !due {
+		return
+	}
+
+	m.doRefresh(rec)
+}
+
+// RefreshNow forces an immediate refresh of one account, clearing its
+// circuit breaker first (that's the point of a manual request).
+func (m *RefreshManager) RefreshNow(email string) error {
+	rec, ok, err := m.store.Get(email)
+	if err != nil {
+		return fmt.Errorf("lookup session for %s: %w", email, err)
+	}
+	if !ok {
+		return fmt.Errorf("no stored session for %s", email)
+	}
+
+	m.mu.Lock()
+	st, ok := m.status[email]
+	if !ok {
+		st = &refreshState{}
+		m.status[email] = st
+	}
+	st.circuitOpen = false
+	st.consecutiveFailures = 0
+	m.mu.Unlock()
+
+	return m.doRefresh(rec)
+}
+
+func (m *RefreshManager) doRefresh(rec SessionRecord) error {
+	accountCfg := accountConfig(m.cfg, rec)
+
+	resp, err := auth.RefreshMaster(accountCfg, rec.MasterToken)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st, ok := m.status[rec.Email]
+	if !ok {
+		st = &refreshState{}
+		m.status[rec.Email] = st
+	}
+
+	if err != nil {
+		st.consecutiveFailures++
+		st.LastError = err.Error()
+		st.Valid = false
+		st.nextAttempt = time.Now().Add(jitter(backoff(m.interval, st.consecutiveFailures)))
+		if st.consecutiveFailures >= circuitBreakThreshold {
+			st.circuitOpen = true
+			log.Printf("[refresh] circuit open for %s after %d consecutive failures", rec.Email, st.consecutiveFailures)
+		}
+		return err
+	}
+
+	st.consecutiveFailures = 0
+	st.circuitOpen = false
+	st.Valid = true
+	st.LastError = ""
+	st.LastRefresh = time.Now()
+	st.nextAttempt = time.Now().Add(jitter(m.interval))
+	if resp.Expiry > 0 {
+		st.ExpiresAt = time.Unix(resp.Expiry, 0)
+	}
+	return nil
+}
+
+// backoff grows the retry delay exponentially with the failure count,
+// capped at 30x the base interval so a long-dead account still gets
+// occasional retries rather than none.
+func backoff(base time.Duration, failures int) time.Duration {
+	const maxMultiplier = 30
+	multiplier := int64(1) << uint(failures)
+	if multiplier > maxMultiplier {
+		multiplier = maxMultiplier
+	}
+	return base * time.Duration(multiplier)
+}
+
+// Status returns the last known health of email's session.
+func (m *RefreshManager) Status(email string) (RefreshStatus, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st, ok := m.status[email]
+	if !ok {
+		return RefreshStatus{}, false
+	}
+	return st.RefreshStatus, true
+}
+
+// accountConfig clones cfg with rec's identity substituted in, so
+// auth.FetchServiceToken/RefreshMaster operate on the stored account rather
+// than whichever account happens to be active in cfg.
+func accountConfig(cfg *config.Config, rec SessionRecord) *config.Config {
+	clone := *cfg
+	clone.Email = rec.Email
+	clone.MasterToken = rec.MasterToken
+	if rec.DeviceID != "" {
+		clone.AndroidID = rec.DeviceID
+	}
+	return &clone
+}
+
+// jitter returns d plus or minus up to 20%, so many accounts on the same
+// interval don't all hit Google's auth endpoint in lockstep.
+func jitter(d time.Duration) time.Duration {
+	delta := time.Duration(rand.Int63n(int64(d) / 5))
+	if rand.Intn(2) == 0 {
+		return d - delta
+	}
+	return d + delta
+}
@@ -3,15 +3,22 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/nicksrandall/gauth/internal/auth"
 	"github.com/nicksrandall/gauth/internal/config"
 )
 
+// refreshInterval is how often RefreshManager re-validates each stored
+// session's master token.
+const refreshInterval = 30 * time.Minute
+
 // TokenRequest is the JSON body for /api/token.
 type TokenRequest struct {
 	Scope      string `json:"scope"`       // OAuth2 scope or service name
@@ -42,11 +49,12 @@ func Start(cfg *config.Config, port int) error {
 
 	// Status endpoint
 	mux.HandleFunc("/api/status", func(w http.ResponseWriter, r *http.Request) {
+		reqCfg := accountConfigForRequest(cfg, r)
 		resp := StatusResponse{
-			Registered: cfg.HasRegistration(),
-			LoggedIn:   cfg.HasMasterToken(),
-			Email:      cfg.Email,
-			AndroidID:  cfg.AndroidID,
+			Registered: reqCfg.HasRegistration(),
+			LoggedIn:   reqCfg.HasMasterToken(),
+			Email:      reqCfg.Email,
+			AndroidID:  reqCfg.AndroidID,
 		}
 		writeJSON(w, http.StatusOK, resp)
 	})
@@ -58,7 +66,8 @@ func Start(cfg *config.Config, port int) error {
 			return
 		}
 
-		if !cfg.HasMasterToken() {
+		reqCfg := accountConfigForRequest(cfg, r)
+		if !reqCfg.HasMasterToken() {
 			writeJSON(w, http.StatusUnauthorized, TokenResponse{Error: "not logged in; run 'gauth login' first"})
 			return
 		}
@@ -97,7 +106,7 @@ func Start(cfg *config.Config, port int) error {
 			req.AppSig = auth.GoogleSig
 		}
 
-		resp, err := auth.FetchServiceToken(cfg, req.Scope, req.AppPackage, req.AppSig)
+		resp, err := auth.GetServiceToken(reqCfg, req.Scope, req.AppPackage, req.AppSig)
 		if err != nil {
 			log.Printf("[server] Token fetch error: %v", err)
 			writeJSON(w, http.StatusInternalServerError, TokenResponse{Error: err.Error()})
@@ -136,21 +145,188 @@ func Start(cfg *config.Config, port int) error {
 		writeJSON(w, http.StatusOK, apps)
 	})
 
+	registerOAuth2Routes(mux, cfg)
+	registerFCMRoutes(mux, cfg)
+	registerPurchaseRoutes(mux, cfg)
+	registerProfileRoutes(mux, cfg)
+	registerCacheRoutes(mux, cfg)
+
 	// Simple web UI
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html")
 		fmt.Fprintf(w, webUI, port)
 	})
 
-	addr := fmt.Sprintf(":%d", port)
-	log.Printf("[gauth] Server starting on http://localhost%s", addr)
+	// Browser-based login: reverse-proxy accounts.google.com under /glogin
+	// with body rewriting, or (when cfg.ProxyMode == "mitm") a transparent
+	// CONNECT forward proxy that terminates TLS with a locally-minted cert.
+	tlsConfig, err := LoadServerTLSConfig()
+	if err != nil {
+		return fmt.Errorf("tls config: %w", err)
+	}
+	scheme := "http"
+	if tlsConfig != nil {
+		scheme = "https"
+	}
+
+	state := NewProxyState()
+	var refreshMgr *RefreshManager
+	if store, err := newDefaultSessionStore(cfg); err != nil {
+		log.Printf("[gauth] session store disabled: %v", err)
+	} else {
+		state.SetStore(store)
+		refreshMgr = NewRefreshManager(cfg, store, refreshInterval)
+		refreshMgr.Start(context.Background())
+	}
+
+	mux.HandleFunc("/api/sessions/", func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.TrimPrefix(r.URL.Path, "/api/sessions/")
+		email, action, ok := strings.Cut(rest, "/")
+		if !ok || email == "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		if refreshMgr == nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "session store not available"})
+			return
+		}
+
+		switch action {
+		case "refresh":
+			if r.Method != http.MethodPost {
+				writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "use POST"})
+				return
+			}
+			if err := refreshMgr.RefreshNow(email); err != nil {
+				writeJSON(w, http.StatusBadGateway, map[string]string{"error": err.Error()})
+				return
+			}
+			status, _ := refreshMgr.Status(email)
+			writeJSON(w, http.StatusOK, status)
+		case "status":
+			status, ok := refreshMgr.Status(email)
+			if !ok {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": "no refresh history for " + email})
+				return
+			}
+			writeJSON(w, http.StatusOK, status)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	mux.Handle("/glogin/", googleProxyHandler(cfg, state, port, scheme))
+	mux.Handle("/gproxy/", staticProxyHandler(cfg, port))
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, loginPageHTML)
+	})
+	mux.HandleFunc("/api/login-status", func(w http.ResponseWriter, r *http.Request) {
+		email, errMsg, captured := state.Result()
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"captured": captured,
+			"email":    email,
+			"error":    errMsg,
+			"accounts": state.Accounts(),
+		})
+	})
+	mux.HandleFunc("/api/proxy-extract", func(w http.ResponseWriter, r *http.Request) {
+		_, _, captured := state.Result()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"success": captured})
+	})
+
+	var handler http.Handler = mux
+	if cfg.ProxyMode == "mitm" {
+		mitmHandler, err := NewMITMProxy(cfg, state)
+		if err != nil {
+			return fmt.Errorf("mitm proxy: %w", err)
+		}
+		handler = connectAwareHandler(mitmHandler, mux)
+		log.Printf("[gauth] Proxy mode: mitm (transparent CONNECT forward proxy)")
+	} else {
+		log.Printf("[gauth] Proxy mode: rewrite (reverse proxy under /glogin)")
+	}
+
+	authCfg := config.LoadAPIAuthConfig("")
+	handler = authMiddleware(cfg, authCfg, handler)
+
+	bindHost := "127.0.0.1"
+	if authCfg.Configured() {
+		bindHost = ""
+	}
+	addr := fmt.Sprintf("%s:%d", bindHost, port)
+	if bindHost == "" {
+		log.Printf("[gauth] Auth configured (api_keys/client_ca/hashcash) — binding all interfaces on port %d", port)
+	} else {
+		log.Printf("[gauth] No auth configured; binding loopback only. Set api_keys, client_ca, or hashcash_enabled in gauth.toml's [auth] table to allow other interfaces.")
+	}
+	log.Printf("[gauth] Server starting on %s://localhost%s", scheme, addr)
 	log.Printf("[gauth] API endpoints:")
 	log.Printf("  GET  /api/status")
 	log.Printf("  POST /api/token  {\"scope\": \"photos\"}")
 	log.Printf("  GET  /api/token?scope=photos")
 	log.Printf("  GET  /api/apps")
+	log.Printf("  GET  /login")
+	log.Printf("  POST /oauth2/token  (grant_type=client_credentials|refresh_token)")
+	log.Printf("  GET  /oauth2/userinfo")
+	log.Printf("  POST /oauth2/introspect")
+	log.Printf("  GET  /.well-known/openid-configuration")
+	log.Printf("  POST /api/fcm/register  {\"sender_id\": \"...\", \"app_package\": \"...\"}")
+	log.Printf("  GET  /api/fcm/stream?app_package=...")
+	log.Printf("  POST /api/fcm/webhook  {\"app_package\": \"...\", \"url\": \"...\"}")
+	log.Printf("  POST /api/verify-purchase  {\"package_name\": \"...\", \"product_id\": \"...\", \"purchase_token\": \"...\"}")
+	log.Printf("  GET  /api/profiles")
+	log.Printf("  POST /api/profiles  {\"email\": \"...\"}  (sets default)")
+	log.Printf("  DELETE /api/profiles?email=...")
+	log.Printf("  Select an account per-request: ?profile=<email> or X-Gauth-Profile header")
+	log.Printf("  GET  /api/cache")
+	log.Printf("  DELETE /api/cache?scope=...")
+	log.Printf("  GET  /metrics  (Prometheus text format)")
+
+	httpServer := &http.Server{Addr: addr, Handler: handler, TLSConfig: tlsConfig}
+	if tlsConfig != nil {
+		return httpServer.ListenAndServeTLS("", "") // cert/key already in TLSConfig.Certificates
+	}
+	return httpServer.ListenAndServe()
+}
+
+// connectAwareHandler routes CONNECT requests to the MITM proxy and
+// everything else to the regular mux. ServeMux can't match CONNECT's
+// authority-form request target, so this has to sit in front of it.
+func connectAwareHandler(mitm, rest http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodConnect {
+			mitm.ServeHTTP(w, r)
+			return
+		}
+		rest.ServeHTTP(w, r)
+	})
+}
 
-	return http.ListenAndServe(addr, mux)
+// accountConfigForRequest lets one `gauth serve` process answer requests for
+// any signed-in account, not just the one it started with. It checks, in
+// order, the "profile" query parameter, the X-Gauth-Profile header, and the
+// older X-Gauth-Account header (kept for compatibility with clients written
+// against it); whichever is set first selects the account. If none are set,
+// cfg is returned as-is. Since ApplyAccount replaces reqCfg.Account wholesale
+// on a copy of cfg rather than cfg itself, concurrent requests for different
+// accounts never share (or race on) the same Account — each request gets its
+// own, and auth.FetchServiceToken only ever reads from the copy it was
+// handed.
+func accountConfigForRequest(cfg *config.Config, r *http.Request) *config.Config {
+	email := r.URL.Query().Get("profile")
+	if email == "" {
+		email = r.Header.Get("X-Gauth-Profile")
+	}
+	if email == "" {
+		email = r.Header.Get("X-Gauth-Account")
+	}
+	if email == "" {
+		return cfg
+	}
+	reqCfg := *cfg
+	reqCfg.ApplyAccount(email)
+	return &reqCfg
 }
 
 func writeJSON(w http.ResponseWriter, status int, v interface{}) {
@@ -0,0 +1,311 @@
+package server
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/pbkdf2"
+
+	"github.com/nicksrandall/gauth/internal/config"
+)
+
+// sessionKeyringService/sessionKeyringUser identify the session-store
+// passphrase in the OS keyring, alongside config/secrets.go's own
+// "config-passphrase" entry under the same service name.
+const (
+	sessionKeyringService = "gauth"
+	sessionKeyringUser    = "session-passphrase"
+)
+
+// SessionRecord is one captured/authenticated Google account.
+type SessionRecord struct {
+	Email       string    `json:"email"`
+	OAuthToken  string    `json:"oauth_token"`
+	MasterToken string    `json:"master_token"`
+	CapturedAt  time.Time `json:"captured_at"`
+	DeviceID    string    `json:"device_id"`
+}
+
+// SessionStore persists captured sessions across restarts, keyed by email.
+// Unlike the old single-account ProxyState, an implementation may hold one
+// record per signed-in Google account.
+type SessionStore interface {
+	Save(rec SessionRecord) error
+	Get(email string) (SessionRecord, bool, error)
+	List() ([]SessionRecord, error)
+}
+
+// maxBlobSize is the largest a single encrypted record blob is allowed to
+// be before it gets split across multiple files on disk, mirroring
+// oauth2_proxy's split-cookie handling for payloads over ~4 KiB (future
+// token bundles — refresh tokens, id_tokens, scoped service tokens — will
+// grow past a single record's current size).
+const maxBlobSize = 4096
+
+// pbkdf2Iterations follows the oauth2_proxy precedent of a fixed, generous
+// iteration count rather than tuning per deployment.
+const pbkdf2Iterations = 100_000
+
+// fileSessionStore is the default SessionStore: one directory holding one
+// (possibly multi-part) encrypted JSON blob per account email.
+type fileSessionStore struct {
+	mu  sync.Mutex
+	dir string
+	key []byte // derived once from the passphrase at construction
+}
+
+// NewFileSessionStore creates a SessionStore rooted at dir, deriving an
+// AES-256-GCM key from passphrase via PBKDF2 with a fixed, store-wide salt
+// file (generated on first use and reused so existing records stay
+// decryptable across restarts).
+func NewFileSessionStore(dir, passphrase string) (SessionStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create session store dir: %w", err)
+	}
+
+	salt, err := loadOrCreateSalt(filepath.Join(dir, "salt"))
+	if err != nil {
+		return nil, err
+	}
+
+	key := pbkdf2.Key([]byte(passphrase), salt, pbkdf2Iterations, 32, sha256.New)
+	return &fileSessionStore{dir: dir, key: key}, nil
+}
+
+func loadOrCreateSalt(path string) ([]byte, error) {
+	if existing, err := os.ReadFile(path); err == nil && len(existing) == 16 {
+		return existing, nil
+	}
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("generate salt: %w", err)
+	}
+	if err := os.WriteFile(path, salt, 0600); err != nil {
+		return nil, fmt.Errorf("write salt: %w", err)
+	}
+	return salt, nil
+}
+
+func (s *fileSessionStore) Save(rec SessionRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rec.CapturedAt.IsZero() {
+		rec.CapturedAt = time.Now()
+	}
+
+	plaintext, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("marshal session record: %w", err)
+	}
+
+	parts, err := s.encryptAndSplit(plaintext)
+	if err != nil {
+		return err
+	}
+
+	base := recordFileBase(rec.Email)
+	// Remove any stale parts from a previously-larger record before writing
+	// the new (possibly shorter) set.
+	existing, _ := filepath.Glob(filepath.Join(s.dir, base+".part*"))
+	for _, f := range existing {
+		os.Remove(f)
+	}
+
+	for i, part := range parts {
+		path := filepath.Join(s.dir, fmt.Sprintf("%s.part%d", base, i))
+		if err := os.WriteFile(path, part, 0600); err != nil {
+			return fmt.Errorf("write session part %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (s *fileSessionStore) Get(email string) (SessionRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	base := recordFileBase(email)
+	parts, err := filepath.Glob(filepath.Join(s.dir, base+".part*"))
+	if err != nil || len(parts) == 0 {
+		return SessionRecord{}, false, nil
+	}
+	sort.Strings(parts) // part0, part1, ... sorts lexically since we never hit part10+ in practice
+
+	var combined []byte
+	for _, p := range parts {
+		blob, err := os.ReadFile(p)
+		if err != nil {
+			return SessionRecord{}, false, fmt.Errorf("read session part %s: %w", p, err)
+		}
+		combined = append(combined, blob...)
+	}
+
+	plaintext, err := s.decrypt(combined)
+	if err != nil {
+		return SessionRecord{}, false, fmt.Errorf("decrypt session for %s: %w", email, err)
+	}
+
+	var rec SessionRecord
+	if err := json.Unmarshal(plaintext, &rec); err != nil {
+		return SessionRecord{}, false, fmt.Errorf("unmarshal session for %s: %w", email, err)
+	}
+	return rec, true, nil
+}
+
+func (s *fileSessionStore) List() ([]SessionRecord, error) {
+	s.mu.Lock()
+	entries, err := os.ReadDir(s.dir)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("list session store dir: %w", err)
+	}
+
+	seen := map[string]bool{}
+	var out []SessionRecord
+	for _, e := range entries {
+		name := e.Name()
+		idx := indexOf(name, ".part")
+		if idx < 0 {
+			continue
+		}
+		base := name[:idx]
+		if seen[base] {
+			continue
+		}
+		seen[base] = true
+
+		email := emailFromFileBase(base)
+		if rec, ok, err := s.Get(email); err == nil && ok {
+			out = append(out, rec)
+		}
+	}
+	return out, nil
+}
+
+// encryptAndSplit AES-GCM-encrypts plaintext as a single sealed blob (one
+// nonce, one ciphertext — GCM needs the whole message to verify the tag) and
+// then chops that sealed blob into maxBlobSize-sized parts for storage.
+func (s *fileSessionStore) encryptAndSplit(plaintext []byte) ([][]byte, error) {
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, fmt.Errorf("new cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("new gcm: %w", err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+
+	var parts [][]byte
+	for len(sealed) > 0 {
+		n := maxBlobSize
+		if n > len(sealed) {
+			n = len(sealed)
+		}
+		parts = append(parts, sealed[:n])
+		sealed = sealed[n:]
+	}
+	return parts, nil
+}
+
+func (s *fileSessionStore) decrypt(sealed []byte) ([]byte, error) {
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, fmt.Errorf("new cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("new gcm: %w", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("sealed blob too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+// recordFileBase turns an email into a filesystem-safe file name prefix.
+func recordFileBase(email string) string {
+	b := []byte(email)
+	for i, c := range b {
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '-' || c == '_' {
+			continue
+		}
+		b[i] = '_'
+	}
+	return string(b)
+}
+
+// emailFromFileBase is a best-effort inverse of recordFileBase used only to
+// decide which Get() to call during List(); Get() loads the real email from
+// the decrypted record, so imperfect round-tripping here is harmless.
+func emailFromFileBase(base string) string {
+	return base
+}
+
+// newDefaultSessionStore builds the SessionStore used by Start. The
+// encryption passphrase comes from GAUTH_SESSION_PASSPHRASE when set (a
+// config-supplied override for a wrapper script or a host with no OS
+// keyring), otherwise it's fetched from — or, on first run, generated and
+// saved to — the OS keyring via go-keyring, the same mechanism
+// config/secrets.go uses for the account secrets passphrase.
+func newDefaultSessionStore(cfg *config.Config) (SessionStore, error) {
+	dir := filepath.Dir(config.ConfigPath())
+	passphrase, err := loadOrCreatePassphrase()
+	if err != nil {
+		return nil, err
+	}
+	return NewFileSessionStore(filepath.Join(dir, "sessions"), passphrase)
+}
+
+// loadOrCreatePassphrase resolves the session-store passphrase: an explicit
+// GAUTH_SESSION_PASSPHRASE wins outright, then a keyring entry from a prior
+// run, then a freshly generated one saved back to the keyring. If no OS
+// keyring is reachable at all (e.g. a headless Linux box with no
+// secret-service) and no env var is set, this fails rather than falling
+// back to a passphrase written in plaintext next to the ciphertext it's
+// meant to protect.
+func loadOrCreatePassphrase() (string, error) {
+	if p := os.Getenv("GAUTH_SESSION_PASSPHRASE"); p != "" {
+		return p, nil
+	}
+	if p, err := keyring.Get(sessionKeyringService, sessionKeyringUser); err == nil && p != "" {
+		return p, nil
+	}
+
+	raw := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, raw); err != nil {
+		return "", fmt.Errorf("generate session passphrase: %w", err)
+	}
+	passphrase := hex.EncodeToString(raw)
+	if err := keyring.Set(sessionKeyringService, sessionKeyringUser, passphrase); err != nil {
+		return "", fmt.Errorf("no OS keyring available (set GAUTH_SESSION_PASSPHRASE instead): %w", err)
+	}
+	return passphrase, nil
+}
+
+func indexOf(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if s[i:i+len(substr)] == substr {
+			return i
+		}
+	}
+	return -1
+}
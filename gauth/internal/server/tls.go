@@ -0,0 +1,185 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nicksrandall/gauth/internal/config"
+)
+
+const (
+	serverCertFile = "server_cert.pem"
+	serverKeyFile  = "server_key.pem"
+)
+
+// LoadServerTLSConfig resolves cfg.TLS (defaults/env/file via
+// config.LoadServerTLSConfig) into a *tls.Config ready for
+// http.Server.ServeTLS, or nil if TLS is off. "auto" generates and caches a
+// self-signed localhost/127.0.0.1 cert next to the config file; "custom"
+// loads CertFile/KeyFile from disk. If [auth].client_ca is set, the
+// resulting config also requires and verifies a client certificate signed
+// by that CA — this is the mTLS mode authMiddleware defers to rather than
+// checking itself, since client certs are validated during the handshake,
+// before any handler (or even authMiddleware) ever runs.
+func LoadServerTLSConfig() (*tls.Config, error) {
+	tlsCfg := config.LoadServerTLSConfig("")
+	authCfg := config.LoadAPIAuthConfig("")
+
+	var base *tls.Config
+	switch tlsCfg.Mode {
+	case "", "off":
+		if authCfg.ClientCA != "" {
+			return nil, fmt.Errorf("auth.client_ca is set but tls is off; mTLS requires tls = \"auto\" or \"custom\"")
+		}
+		return nil, nil
+
+	case "custom":
+		if tlsCfg.CertFile == "" || tlsCfg.KeyFile == "" {
+			return nil, fmt.Errorf("tls = \"custom\" requires cert_file and key_file")
+		}
+		cert, err := tls.LoadX509KeyPair(tlsCfg.CertFile, tlsCfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load custom tls cert: %w", err)
+		}
+		base = &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	case "auto":
+		cert, err := loadOrCreateServerCert()
+		if err != nil {
+			return nil, fmt.Errorf("auto tls cert: %w", err)
+		}
+		base = &tls.Config{Certificates: []tls.Certificate{*cert}}
+
+	default:
+		return nil, fmt.Errorf("unknown tls mode %q (want auto, off, or custom)", tlsCfg.Mode)
+	}
+
+	if authCfg.ClientCA != "" {
+		if err := applyClientCA(base, authCfg.ClientCA); err != nil {
+			return nil, err
+		}
+	}
+	return base, nil
+}
+
+// applyClientCA loads the PEM-encoded CA bundle at path and configures
+// tlsCfg to require and verify a client certificate signed by it.
+func applyClientCA(tlsCfg *tls.Config, path string) error {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read client_ca: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemBytes) {
+		return fmt.Errorf("client_ca %s: no certificates found", path)
+	}
+	tlsCfg.ClientCAs = pool
+	tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+	return nil
+}
+
+func loadOrCreateServerCert() (*tls.Certificate, error) {
+	dir := filepath.Dir(config.ConfigPath())
+	certPath := filepath.Join(dir, serverCertFile)
+	keyPath := filepath.Join(dir, serverKeyFile)
+
+	if cert, err := tls.LoadX509KeyPair(certPath, keyPath); err == nil {
+		return &cert, nil
+	}
+
+	certPEM, keyPEM, err := generateServerCert()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create config dir: %w", err)
+	}
+	if err := os.WriteFile(certPath, certPEM, 0644); err != nil {
+		return nil, fmt.Errorf("write server cert: %w", err)
+	}
+	if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		return nil, fmt.Errorf("write server key: %w", err)
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("parse generated cert: %w", err)
+	}
+	return &cert, nil
+}
+
+func generateServerCert() (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate server key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate serial: %w", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "localhost", Organization: []string{"gauth"}},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(2, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create server cert: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("marshal server key: %w", err)
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+// ServerCertPath returns where the auto-generated cert lives, for the
+// `gauth trust install` subcommand.
+func ServerCertPath() string {
+	return filepath.Join(filepath.Dir(config.ConfigPath()), serverCertFile)
+}
+
+// EnsureServerCert generates the auto-generated cert if it doesn't already
+// exist, regardless of the configured tls mode, so `gauth trust install` has
+// something to install even before the server has ever been started.
+func EnsureServerCert() error {
+	_, err := loadOrCreateServerCert()
+	return err
+}
+
+// trustInstallError wraps a failed OS trust-store command with enough
+// context (which store, what the tool printed) for `gauth trust install` to
+// give the user something actionable.
+type trustInstallError struct {
+	platform string
+	output   string
+	err      error
+}
+
+func (e *trustInstallError) Error() string {
+	return fmt.Sprintf("install into %s: %v: %s", e.platform, e.err, e.output)
+}
+
+func (e *trustInstallError) Unwrap() error { return e.err }
@@ -0,0 +1,17 @@
+//go:build darwin
+
+package server
+
+import "os/exec"
+
+// InstallTrust adds certPath to the macOS System keychain as a trusted root,
+// for `gauth trust install`.
+func InstallTrust(certPath string) error {
+	cmd := exec.Command("security", "add-trusted-cert", "-d", "-r", "trustRoot",
+		"-k", "/Library/Keychains/System.keychain", certPath)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return &trustInstallError{platform: "macOS keychain", output: string(out), err: err}
+	}
+	return nil
+}
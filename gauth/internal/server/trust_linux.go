@@ -0,0 +1,70 @@
+//go:build linux
+
+package server
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// InstallTrust copies certPath into the system CA bundle (update-ca-certificates)
+// and, if certutil is available, into the NSS database Chrome/Chromium use —
+// Google's embedded setup pages are typically driven through a Chromium-based
+// webview, which ignores the system store on Linux.
+func InstallTrust(certPath string) error {
+	if err := installSystemCA(certPath); err != nil {
+		return err
+	}
+	if err := installNSSCert(certPath); err != nil {
+		// Not fatal: system CA trust still covers non-NSS clients.
+		return &trustInstallError{platform: "NSS (Chromium) database", output: err.Error(), err: err}
+	}
+	return nil
+}
+
+func installSystemCA(certPath string) error {
+	dest := "/usr/local/share/ca-certificates/gauth-server.crt"
+	if err := copyFile(certPath, dest); err != nil {
+		return fmt.Errorf("copy cert to %s: %w", dest, err)
+	}
+	out, err := exec.Command("update-ca-certificates").CombinedOutput()
+	if err != nil {
+		return &trustInstallError{platform: "system CA bundle", output: string(out), err: err}
+	}
+	return nil
+}
+
+func installNSSCert(certPath string) error {
+	certutil, err := exec.LookPath("certutil")
+	if err != nil {
+		return fmt.Errorf("certutil not installed (apt install libnss3-tools): %w", err)
+	}
+	nssDB := filepath.Join(os.Getenv("HOME"), ".pki/nssdb")
+	cmd := exec.Command(certutil, "-d", "sql:"+nssDB, "-A", "-t", "C,,",
+		"-n", "gauth local server", "-i", certPath)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %w", string(out), err)
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
@@ -0,0 +1,16 @@
+//go:build windows
+
+package server
+
+import "os/exec"
+
+// InstallTrust adds certPath to the current user's Trusted Root
+// Certification Authorities store via certutil, for `gauth trust install`.
+func InstallTrust(certPath string) error {
+	cmd := exec.Command("certutil", "-user", "-addstore", "Root", certPath)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return &trustInstallError{platform: "Windows certificate store", output: string(out), err: err}
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package store
+
+import "github.com/nicksrandall/gauth/internal/login"
+
+// freshLoginProvider mirrors cmd/gauth's resolveLoginProvider: WebView2
+// isn't available outside Windows, so LoadOrLogin falls back to the
+// loopback browser flow.
+func freshLoginProvider() login.Provider {
+	return login.LoopbackProvider{}
+}
@@ -0,0 +1,12 @@
+//go:build windows
+
+package store
+
+import "github.com/nicksrandall/gauth/internal/login"
+
+// freshLoginProvider mirrors cmd/gauth's resolveLoginProvider: WebView2 is
+// available on this build, so LoadOrLogin prefers it over the loopback
+// browser flow.
+func freshLoginProvider() login.Provider {
+	return login.WebView2Provider{}
+}
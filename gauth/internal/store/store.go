@@ -0,0 +1,96 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/nicksrandall/gauth/internal/auth"
+	"github.com/nicksrandall/gauth/internal/checkin"
+	"github.com/nicksrandall/gauth/internal/config"
+)
+
+// LoadOrLogin returns credentials for email, from the OS-keychain-backed
+// Vault if a cached master token there still validates against Google, or
+// by running a fresh device check-in + sign-in otherwise. Either way cfg is
+// left populated with the resulting identity and a successful fresh login
+// is persisted to the vault — the "optionally persist on success" this
+// package exists for happens here rather than inside RunWebViewLogin
+// itself, since internal/login can't import internal/store without the
+// reverse import (freshLoginProvider) creating a cycle.
+func LoadOrLogin(cfg *config.Config, email string) (*Credentials, error) {
+	vault, err := NewVault()
+	if err != nil {
+		return nil, fmt.Errorf("open vault: %w", err)
+	}
+
+	if creds, ok, err := vault.Load(email); err == nil && ok {
+		applyCredentials(cfg, creds)
+		if _, err := auth.RefreshMaster(cfg, creds.MasterToken); err == nil {
+			return &creds, nil
+		}
+		// Cached master token no longer validates (revoked, expired, or
+		// Google rejected it) — fall through to a fresh login.
+	}
+
+	if err := doCheckin(cfg); err != nil {
+		return nil, fmt.Errorf("check-in: %w", err)
+	}
+
+	result, err := freshLoginProvider().Start(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("login: %w", err)
+	}
+
+	resp, err := auth.ExchangeOAuthForMaster(cfg, result.OAuthToken)
+	if err != nil {
+		return nil, fmt.Errorf("exchange oauth for master token: %w", err)
+	}
+	masterToken := resp.Token
+	if masterToken == "" {
+		masterToken = resp.Auth
+	}
+	if masterToken == "" {
+		return nil, fmt.Errorf("no master token in response: %s", resp.Error)
+	}
+
+	creds := Credentials{
+		Email:         resp.Email,
+		OAuthToken:    result.OAuthToken,
+		MasterToken:   masterToken,
+		AndroidID:     cfg.AndroidID,
+		SecurityToken: cfg.SecurityToken,
+	}
+	applyCredentials(cfg, creds)
+
+	if err := vault.Save(creds.Email, creds); err != nil {
+		return nil, fmt.Errorf("save credentials to vault: %w", err)
+	}
+	return &creds, nil
+}
+
+// applyCredentials copies creds onto cfg, the same fields cmdLogin sets
+// directly after a successful sign-in.
+func applyCredentials(cfg *config.Config, creds Credentials) {
+	cfg.Email = creds.Email
+	cfg.MasterToken = creds.MasterToken
+	if creds.AndroidID != "" {
+		cfg.AndroidID = creds.AndroidID
+	}
+	if creds.SecurityToken != "" {
+		cfg.SecurityToken = creds.SecurityToken
+	}
+}
+
+// doCheckin performs device check-in if cfg doesn't already have one,
+// mirroring cmd/gauth's doCheckin.
+func doCheckin(cfg *config.Config) error {
+	if cfg.HasRegistration() {
+		return nil
+	}
+	result, err := checkin.Checkin(cfg)
+	if err != nil {
+		return err
+	}
+	cfg.AndroidID = fmt.Sprintf("%x", result.AndroidID)
+	cfg.SecurityToken = fmt.Sprintf("%d", result.SecurityToken)
+	return nil
+}
@@ -0,0 +1,41 @@
+// Package store persists signed-in credentials to the OS's native secret
+// storage — Windows DPAPI, Linux libsecret, macOS Keychain — so a cached
+// master token survives across runs without the caller ever handling its
+// own encryption passphrase. LoadOrLogin is the entry point most callers
+// want; Vault is the lower-level per-account get/set/delete interface its
+// platform-specific implementations satisfy.
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Credentials is everything LoadOrLogin needs to skip both sign-in and
+// device check-in on a later run.
+type Credentials struct {
+	Email         string
+	OAuthToken    string
+	MasterToken   string
+	AndroidID     string
+	SecurityToken string
+}
+
+// Vault stores one Credentials record per email in the OS's native secret
+// storage. NewVault returns the implementation for the current GOOS; see
+// vault_windows.go, vault_linux.go, vault_darwin.go and the vault_other.go
+// fallback for platforms without one of those.
+type Vault interface {
+	Save(email string, creds Credentials) error
+	Load(email string) (Credentials, bool, error)
+	Delete(email string) error
+}
+
+// vaultKey turns an email into the opaque, filesystem-safe identifier the
+// OS-keychain implementations use to name an entry — hashed rather than
+// sanitized so it never collides with another account's record or exposes
+// the email in a directory listing.
+func vaultKey(email string) string {
+	sum := sha256.Sum256([]byte(email))
+	return hex.EncodeToString(sum[:])
+}
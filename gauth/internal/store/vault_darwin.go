@@ -0,0 +1,69 @@
+//go:build darwin
+
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+)
+
+// keychainVault shells out to the security CLI, the command-line entry
+// point to the macOS Keychain, mirroring server.InstallTrust's precedent
+// of shelling out to a platform CLI rather than using cgo to bind the
+// native Security framework.
+type keychainVault struct{}
+
+const keychainService = "gauth"
+
+// NewVault returns the macOS Keychain-backed Vault.
+func NewVault() (Vault, error) {
+	if _, err := exec.LookPath("security"); err != nil {
+		return nil, fmt.Errorf("security CLI not found: %w", err)
+	}
+	return keychainVault{}, nil
+}
+
+func (keychainVault) Save(email string, creds Credentials) error {
+	plaintext, err := json.Marshal(creds)
+	if err != nil {
+		return fmt.Errorf("marshal credentials: %w", err)
+	}
+
+	// add-generic-password has no "upsert" flag; delete any existing entry
+	// first so a later login for the same account replaces it instead of
+	// failing with "already exists".
+	_ = exec.Command("security", "delete-generic-password", "-s", keychainService, "-a", vaultKey(email)).Run()
+
+	cmd := exec.Command("security", "add-generic-password",
+		"-s", keychainService,
+		"-a", vaultKey(email),
+		"-w", string(plaintext),
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("security add-generic-password: %w: %s", err, out)
+	}
+	return nil
+}
+
+func (keychainVault) Load(email string) (Credentials, bool, error) {
+	cmd := exec.Command("security", "find-generic-password", "-s", keychainService, "-a", vaultKey(email), "-w")
+	out, err := cmd.Output()
+	if err != nil {
+		return Credentials{}, false, nil
+	}
+	if len(out) == 0 {
+		return Credentials{}, false, nil
+	}
+
+	var creds Credentials
+	if err := json.Unmarshal(out, &creds); err != nil {
+		return Credentials{}, false, fmt.Errorf("unmarshal credentials: %w", err)
+	}
+	return creds, true, nil
+}
+
+func (keychainVault) Delete(email string) error {
+	_ = exec.Command("security", "delete-generic-password", "-s", keychainService, "-a", vaultKey(email)).Run()
+	return nil
+}
@@ -0,0 +1,73 @@
+//go:build linux
+
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+)
+
+// secretToolVault shells out to secret-tool (part of libsecret-tools),
+// the same command-line entry point to the desktop keyring GNOME/KDE
+// expose, mirroring server.InstallTrust's precedent of shelling out to a
+// platform CLI rather than binding the native library directly.
+type secretToolVault struct{}
+
+// NewVault returns the Linux libsecret-backed Vault.
+func NewVault() (Vault, error) {
+	if _, err := exec.LookPath("secret-tool"); err != nil {
+		return nil, fmt.Errorf("secret-tool not found (install libsecret-tools): %w", err)
+	}
+	return secretToolVault{}, nil
+}
+
+func (secretToolVault) attrs(email string) []string {
+	return []string{"service", "gauth", "account", vaultKey(email)}
+}
+
+func (v secretToolVault) Save(email string, creds Credentials) error {
+	plaintext, err := json.Marshal(creds)
+	if err != nil {
+		return fmt.Errorf("marshal credentials: %w", err)
+	}
+
+	args := append([]string{"store", "--label", "gauth credentials (" + email + ")"}, v.attrs(email)...)
+	cmd := exec.Command("secret-tool", args...)
+	cmd.Stdin = bytes.NewReader(plaintext)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("secret-tool store: %w: %s", err, out)
+	}
+	return nil
+}
+
+func (v secretToolVault) Load(email string) (Credentials, bool, error) {
+	cmd := exec.Command("secret-tool", append([]string{"lookup"}, v.attrs(email)...)...)
+	out, err := cmd.Output()
+	if err != nil {
+		// secret-tool exits non-zero with empty output when there's no
+		// matching entry; anything else is a real failure.
+		if len(out) == 0 {
+			return Credentials{}, false, nil
+		}
+		return Credentials{}, false, fmt.Errorf("secret-tool lookup: %w", err)
+	}
+	if len(out) == 0 {
+		return Credentials{}, false, nil
+	}
+
+	var creds Credentials
+	if err := json.Unmarshal(out, &creds); err != nil {
+		return Credentials{}, false, fmt.Errorf("unmarshal credentials: %w", err)
+	}
+	return creds, true, nil
+}
+
+func (v secretToolVault) Delete(email string) error {
+	cmd := exec.Command("secret-tool", append([]string{"clear"}, v.attrs(email)...)...)
+	// secret-tool clear exits non-zero when there's nothing to clear; that's
+	// not an error worth surfacing to the caller.
+	_ = cmd.Run()
+	return nil
+}
@@ -0,0 +1,147 @@
+//go:build !windows && !linux && !darwin
+
+package store
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/pbkdf2"
+
+	"github.com/nicksrandall/gauth/internal/config"
+)
+
+// fallbackVault is what NewVault returns on a GOOS without a native secret
+// store implementation above: AES-256-GCM with a PBKDF2-derived key, the
+// same "not a real OS keychain, just an honest substitute" construction
+// server.newDefaultSessionStore uses for session records.
+type fallbackVault struct {
+	dir string
+	key []byte
+}
+
+// NewVault returns the AES-GCM+PBKDF2 fallback Vault. It is not backed by
+// any OS secret store — there isn't one for this GOOS — so it's only as
+// safe as the filesystem permissions on dir.
+func NewVault() (Vault, error) {
+	dir := filepath.Join(filepath.Dir(config.ConfigPath()), "vault")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create vault dir: %w", err)
+	}
+
+	salt, err := loadOrCreateSalt(filepath.Join(dir, "salt"))
+	if err != nil {
+		return nil, err
+	}
+	passphrase, err := loadOrCreatePassphrase(filepath.Join(dir, "passphrase"))
+	if err != nil {
+		return nil, err
+	}
+	key := pbkdf2.Key([]byte(passphrase), salt, 100_000, 32, sha256.New)
+	return &fallbackVault{dir: dir, key: key}, nil
+}
+
+func loadOrCreateSalt(path string) ([]byte, error) {
+	if existing, err := os.ReadFile(path); err == nil && len(existing) == 16 {
+		return existing, nil
+	}
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("generate salt: %w", err)
+	}
+	if err := os.WriteFile(path, salt, 0600); err != nil {
+		return nil, fmt.Errorf("write salt: %w", err)
+	}
+	return salt, nil
+}
+
+func loadOrCreatePassphrase(path string) (string, error) {
+	if existing, err := os.ReadFile(path); err == nil {
+		return string(existing), nil
+	}
+	raw := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, raw); err != nil {
+		return "", fmt.Errorf("generate vault passphrase: %w", err)
+	}
+	passphrase := hex.EncodeToString(raw)
+	if err := os.WriteFile(path, []byte(passphrase), 0600); err != nil {
+		return "", fmt.Errorf("write vault passphrase: %w", err)
+	}
+	return passphrase, nil
+}
+
+func (v *fallbackVault) path(email string) string {
+	return filepath.Join(v.dir, vaultKey(email)+".enc")
+}
+
+func (v *fallbackVault) Save(email string, creds Credentials) error {
+	plaintext, err := json.Marshal(creds)
+	if err != nil {
+		return fmt.Errorf("marshal credentials: %w", err)
+	}
+
+	block, err := aes.NewCipher(v.key)
+	if err != nil {
+		return fmt.Errorf("new cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return fmt.Errorf("new gcm: %w", err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return fmt.Errorf("generate nonce: %w", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+
+	return os.WriteFile(v.path(email), sealed, 0600)
+}
+
+func (v *fallbackVault) Load(email string) (Credentials, bool, error) {
+	sealed, err := os.ReadFile(v.path(email))
+	if os.IsNotExist(err) {
+		return Credentials{}, false, nil
+	}
+	if err != nil {
+		return Credentials{}, false, fmt.Errorf("read vault entry: %w", err)
+	}
+
+	block, err := aes.NewCipher(v.key)
+	if err != nil {
+		return Credentials{}, false, fmt.Errorf("new cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return Credentials{}, false, fmt.Errorf("new gcm: %w", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return Credentials{}, false, fmt.Errorf("vault entry too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return Credentials{}, false, fmt.Errorf("decrypt vault entry: %w", err)
+	}
+
+	var creds Credentials
+	if err := json.Unmarshal(plaintext, &creds); err != nil {
+		return Credentials{}, false, fmt.Errorf("unmarshal credentials: %w", err)
+	}
+	return creds, true, nil
+}
+
+func (v *fallbackVault) Delete(email string) error {
+	err := os.Remove(v.path(email))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,137 @@
+//go:build windows
+
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/nicksrandall/gauth/internal/config"
+)
+
+// dpapiVault encrypts each account's Credentials with DPAPI
+// (CryptProtectData), the same current-user-scoped encryption Windows
+// Credential Manager itself is built on: only the Windows account that
+// wrote an entry can decrypt it, with no passphrase of our own to manage.
+type dpapiVault struct {
+	dir string
+}
+
+// NewVault returns the Windows DPAPI-backed Vault.
+func NewVault() (Vault, error) {
+	dir := filepath.Join(filepath.Dir(config.ConfigPath()), "vault")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create vault dir: %w", err)
+	}
+	return &dpapiVault{dir: dir}, nil
+}
+
+func (v *dpapiVault) path(email string) string {
+	return filepath.Join(v.dir, vaultKey(email)+".dpapi")
+}
+
+func (v *dpapiVault) Save(email string, creds Credentials) error {
+	plaintext, err := json.Marshal(creds)
+	if err != nil {
+		return fmt.Errorf("marshal credentials: %w", err)
+	}
+	ciphertext, err := dpapiProtect(plaintext)
+	if err != nil {
+		return fmt.Errorf("dpapi protect: %w", err)
+	}
+	return os.WriteFile(v.path(email), ciphertext, 0600)
+}
+
+func (v *dpapiVault) Load(email string) (Credentials, bool, error) {
+	ciphertext, err := os.ReadFile(v.path(email))
+	if os.IsNotExist(err) {
+		return Credentials{}, false, nil
+	}
+	if err != nil {
+		return Credentials{}, false, fmt.Errorf("read vault entry: %w", err)
+	}
+
+	plaintext, err := dpapiUnprotect(ciphertext)
+	if err != nil {
+		return Credentials{}, false, fmt.Errorf("dpapi unprotect: %w", err)
+	}
+	var creds Credentials
+	if err := json.Unmarshal(plaintext, &creds); err != nil {
+		return Credentials{}, false, fmt.Errorf("unmarshal credentials: %w", err)
+	}
+	return creds, true, nil
+}
+
+func (v *dpapiVault) Delete(email string) error {
+	err := os.Remove(v.path(email))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+var (
+	modcrypt32             = windows.NewLazySystemDLL("crypt32.dll")
+	modkernel32            = windows.NewLazySystemDLL("kernel32.dll")
+	procCryptProtectData   = modcrypt32.NewProc("CryptProtectData")
+	procCryptUnprotectData = modcrypt32.NewProc("CryptUnprotectData")
+	procLocalFree          = modkernel32.NewProc("LocalFree")
+)
+
+// cryptBlob mirrors Windows' CRYPT_INTEGER_BLOB / DATA_BLOB layout, the
+// struct CryptProtectData and CryptUnprotectData exchange buffers through.
+type cryptBlob struct {
+	size uint32
+	data *byte
+}
+
+func newCryptBlob(b []byte) *cryptBlob {
+	if len(b) == 0 {
+		return &cryptBlob{}
+	}
+	return &cryptBlob{size: uint32(len(b)), data: &b[0]}
+}
+
+func (b *cryptBlob) bytes() []byte {
+	if b.data == nil || b.size == 0 {
+		return nil
+	}
+	out := make([]byte, b.size)
+	copy(out, unsafe.Slice(b.data, b.size))
+	return out
+}
+
+func dpapiProtect(plaintext []byte) ([]byte, error) {
+	in := newCryptBlob(plaintext)
+	var out cryptBlob
+	r, _, err := procCryptProtectData.Call(
+		uintptr(unsafe.Pointer(in)),
+		0, 0, 0, 0, 0,
+		uintptr(unsafe.Pointer(&out)),
+	)
+	if r == 0 {
+		return nil, fmt.Errorf("CryptProtectData: %w", err)
+	}
+	defer procLocalFree.Call(uintptr(unsafe.Pointer(out.data)))
+	return out.bytes(), nil
+}
+
+func dpapiUnprotect(ciphertext []byte) ([]byte, error) {
+	in := newCryptBlob(ciphertext)
+	var out cryptBlob
+	r, _, err := procCryptUnprotectData.Call(
+		uintptr(unsafe.Pointer(in)),
+		0, 0, 0, 0, 0,
+		uintptr(unsafe.Pointer(&out)),
+	)
+	if r == 0 {
+		return nil, fmt.Errorf("CryptUnprotectData: %w", err)
+	}
+	defer procLocalFree.Call(uintptr(unsafe.Pointer(out.data)))
+	return out.bytes(), nil
+}
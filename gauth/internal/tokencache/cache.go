@@ -0,0 +1,213 @@
+// Package tokencache is a fixed-capacity, in-memory LRU of recently
+// fetched service tokens. It sits in front of auth's file-backed
+// TokenCache so a hot /api/token request doesn't pay for a disk read and
+// cross-process lock on every call, and it tracks hit/miss/refresh counts
+// and fetch latency for a Prometheus /metrics endpoint.
+package tokencache
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SafetyMargin is how far ahead of an entry's ExpiresAt Status starts
+// reporting NearExpiry instead of Fresh, so a caller can serve the cached
+// token while refreshing it in the background well before Google actually
+// stops honoring it. 5 minutes comfortably covers the time a refresh round
+// trip takes.
+var SafetyMargin = 5 * time.Minute
+
+// Entry is one cached token, keyed by Key(profile, appPackage, appSig, scope).
+type Entry struct {
+	Token         string
+	GrantedScopes string
+	ExpiresAt     time.Time
+}
+
+// Status classifies an Entry relative to now and SafetyMargin.
+type Status int
+
+const (
+	// Fresh entries can be served as-is.
+	Fresh Status = iota
+	// NearExpiry entries are still valid but within SafetyMargin of
+	// ExpiresAt — serve them, but refresh in the background.
+	NearExpiry
+	// Expired entries are past ExpiresAt and must be refreshed before
+	// being served.
+	Expired
+)
+
+// Status classifies e relative to now and SafetyMargin.
+func (e Entry) Status(now time.Time) Status {
+	switch {
+	case e.ExpiresAt.IsZero() || !now.Before(e.ExpiresAt):
+		return Expired
+	case now.Before(e.ExpiresAt.Add(-SafetyMargin)):
+		return Fresh
+	default:
+		return NearExpiry
+	}
+}
+
+// Key builds the cache key for a (profile, appPackage, appSig, scope)
+// tuple. appSig is included because the same scope requested under a
+// different app signature gets a token tied to that signature.
+func Key(profile, appPackage, appSig, scope string) string {
+	return strings.Join([]string{profile, appPackage, appSig, scope}, "|")
+}
+
+type node struct {
+	key   string
+	entry Entry
+}
+
+// Cache is a fixed-capacity, least-recently-used in-memory cache, safe for
+// concurrent use.
+type Cache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+
+	hits, misses, asyncRefreshes, blockingRefreshes atomic.Int64
+	fetchMicros                                     atomic.Int64
+	fetchCount                                      atomic.Int64
+}
+
+// NewCache returns an empty Cache holding at most capacity entries.
+func NewCache(capacity int) *Cache {
+	return &Cache{capacity: capacity, ll: list.New(), items: map[string]*list.Element{}}
+}
+
+// Get returns the cached entry for key, if any. It doesn't evaluate
+// freshness — callers compare the result against time.Now() via
+// Entry.Status themselves, since only the caller knows whether a
+// near-expiry hit should trigger an async or blocking refresh.
+func (c *Cache) Get(key string) (Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		c.misses.Add(1)
+		return Entry{}, false
+	}
+	c.ll.MoveToFront(el)
+	c.hits.Add(1)
+	return el.Value.(*node).entry, true
+}
+
+// Set inserts or updates the entry for key, evicting the least recently
+// used entry if the cache is at capacity.
+func (c *Cache) Set(key string, e Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		el.Value.(*node).entry = e
+		c.ll.MoveToFront(el)
+		return
+	}
+	el := c.ll.PushFront(&node{key: key, entry: e})
+	c.items[key] = el
+	if c.capacity > 0 && c.ll.Len() > c.capacity {
+		if oldest := c.ll.Back(); oldest != nil {
+			c.ll.Remove(oldest)
+			delete(c.items, oldest.Value.(*node).key)
+		}
+	}
+}
+
+// DeleteScope removes every entry whose key ends in "|"+scope (the fourth
+// Key field), or every entry if scope is empty — mirroring
+// auth.TokenCache.Clear's semantics for DELETE /api/cache?scope=.
+func (c *Cache) DeleteScope(scope string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if scope == "" {
+		c.ll.Init()
+		c.items = map[string]*list.Element{}
+		return
+	}
+	for key, el := range c.items {
+		if strings.HasSuffix(key, "|"+scope) {
+			c.ll.Remove(el)
+			delete(c.items, key)
+		}
+	}
+}
+
+// Len returns the number of entries currently cached.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// RecordAsyncRefresh counts a NearExpiry hit that triggered a background
+// refresh instead of blocking the caller.
+func (c *Cache) RecordAsyncRefresh() { c.asyncRefreshes.Add(1) }
+
+// RecordBlockingRefresh counts a miss or Expired hit that blocked the
+// caller on a fresh fetch.
+func (c *Cache) RecordBlockingRefresh() { c.blockingRefreshes.Add(1) }
+
+// RecordFetch records how long a round trip to Google took, for the
+// gauth_tokencache_fetch_duration_seconds_{sum,count} metrics.
+func (c *Cache) RecordFetch(d time.Duration) {
+	c.fetchMicros.Add(d.Microseconds())
+	c.fetchCount.Add(1)
+}
+
+// Stats is a point-in-time snapshot of Cache's counters, for GET /api/cache.
+type Stats struct {
+	Entries           int   `json:"entries"`
+	Hits              int64 `json:"hits"`
+	Misses            int64 `json:"misses"`
+	AsyncRefreshes    int64 `json:"async_refreshes"`
+	BlockingRefreshes int64 `json:"blocking_refreshes"`
+}
+
+// Stats returns a snapshot of c's current size and counters.
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Entries:           c.Len(),
+		Hits:              c.hits.Load(),
+		Misses:            c.misses.Load(),
+		AsyncRefreshes:    c.asyncRefreshes.Load(),
+		BlockingRefreshes: c.blockingRefreshes.Load(),
+	}
+}
+
+// WriteMetrics writes c's counters to w in Prometheus text exposition
+// format, for GET /metrics.
+func (c *Cache) WriteMetrics(w io.Writer) {
+	fmt.Fprintf(w, "# HELP gauth_tokencache_entries Number of tokens currently cached in memory.\n")
+	fmt.Fprintf(w, "# TYPE gauth_tokencache_entries gauge\n")
+	fmt.Fprintf(w, "gauth_tokencache_entries %d\n", c.Len())
+
+	fmt.Fprintf(w, "# HELP gauth_tokencache_hits_total Cache lookups that found a cached token.\n")
+	fmt.Fprintf(w, "# TYPE gauth_tokencache_hits_total counter\n")
+	fmt.Fprintf(w, "gauth_tokencache_hits_total %d\n", c.hits.Load())
+
+	fmt.Fprintf(w, "# HELP gauth_tokencache_misses_total Cache lookups that found nothing.\n")
+	fmt.Fprintf(w, "# TYPE gauth_tokencache_misses_total counter\n")
+	fmt.Fprintf(w, "gauth_tokencache_misses_total %d\n", c.misses.Load())
+
+	fmt.Fprintf(w, "# HELP gauth_tokencache_async_refreshes_total Near-expiry hits served from cache while refreshing in the background.\n")
+	fmt.Fprintf(w, "# TYPE gauth_tokencache_async_refreshes_total counter\n")
+	fmt.Fprintf(w, "gauth_tokencache_async_refreshes_total %d\n", c.asyncRefreshes.Load())
+
+	fmt.Fprintf(w, "# HELP gauth_tokencache_blocking_refreshes_total Misses or hard-expired hits that blocked on a fresh fetch.\n")
+	fmt.Fprintf(w, "# TYPE gauth_tokencache_blocking_refreshes_total counter\n")
+	fmt.Fprintf(w, "gauth_tokencache_blocking_refreshes_total %d\n", c.blockingRefreshes.Load())
+
+	fmt.Fprintf(w, "# HELP gauth_tokencache_fetch_duration_seconds Time spent fetching a fresh token from Google.\n")
+	fmt.Fprintf(w, "# TYPE gauth_tokencache_fetch_duration_seconds summary\n")
+	fmt.Fprintf(w, "gauth_tokencache_fetch_duration_seconds_sum %f\n", float64(c.fetchMicros.Load())/1e6)
+	fmt.Fprintf(w, "gauth_tokencache_fetch_duration_seconds_count %d\n", c.fetchCount.Load())
+}